@@ -1,12 +1,13 @@
 module github.com/mandelsoft/vfs
 
-go 1.22.5
+go 1.23
 
 require (
 	github.com/mandelsoft/filepath v0.0.0-20240223090642-3e2777258aa3
 	github.com/modern-go/reflect2 v1.0.2
 	github.com/onsi/ginkgo v1.14.0
 	github.com/onsi/gomega v1.10.1
+	golang.org/x/sys v0.9.0
 	gopkg.in/yaml.v2 v2.3.0
 )
 
@@ -14,7 +15,6 @@ require (
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
 	github.com/nxadm/tail v1.4.4 // indirect
 	golang.org/x/net v0.11.0 // indirect
-	golang.org/x/sys v0.9.0 // indirect
 	golang.org/x/text v0.10.0 // indirect
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
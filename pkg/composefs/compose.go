@@ -19,44 +19,119 @@
 package composefs
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/mandelsoft/vfs/pkg/osfs"
+	"github.com/mandelsoft/vfs/pkg/projectionfs"
+	"github.com/mandelsoft/vfs/pkg/readonlyfs"
 	"github.com/mandelsoft/vfs/pkg/utils"
 	"github.com/mandelsoft/vfs/pkg/vfs"
 )
 
+// ErrNotMounted is returned for an unmount of a path,
+// which is no mount point.
+var ErrNotMounted = errors.New("not a mount point")
+
+// ErrLinkOutsideMount is returned for the evaluation of a symbolic
+// link leaving a mount with option NoSymlinkEscape.
+var ErrLinkOutsideMount = errors.New("symbolic link leaves mount")
+
 type ComposedFileSystem struct {
 	*utils.MappedFileSystem
-	mounts  map[string]vfs.FileSystem
+	lock    sync.RWMutex
+	mounts  mountTable
 	tempdir string
 }
 
+// MountOptions describes the options for mounting a filesystem.
+type MountOptions struct {
+	// ReadOnly mounts the filesystem read-only.
+	ReadOnly bool
+	// SubDir binds a directory of the filesystem instead of its root.
+	SubDir string
+	// NoSymlinkEscape rejects the evaluation of symbolic links
+	// leaving the mount.
+	NoSymlinkEscape bool
+	// NoExec masks the executable bits of all non-directory
+	// entries of the filesystem.
+	NoExec bool
+}
+
+// mount is an entry of the mount table.
+type mount struct {
+	path    string
+	fs      vfs.FileSystem
+	options MountOptions
+	// effective is the filesystem used to access the mount.
+	effective vfs.FileSystem
+	// open is the number of open files.
+	open int
+}
+
+// MountPoint describes a filesystem mounted
+// into a ComposedFileSystem.
+type MountPoint struct {
+	Path       string
+	FileSystem vfs.FileSystem
+	Options    MountOptions
+}
+
 type adapter struct {
 	fs *ComposedFileSystem
 }
 
 func (a *adapter) MapPath(path string) (vfs.FileSystem, string) {
-	var mountp string
-	var mountfs vfs.FileSystem
+	a.fs.lock.RLock()
+	defer a.fs.lock.RUnlock()
 
-	for p, fs := range a.fs.mounts {
-		if p == path {
-			return fs, vfs.PathSeparatorString
-		}
+	m := a.fs.mounts.lookup(path)
+	switch {
+	case m == nil:
+		return a.fs.Base(), path
+	case m.path == path:
+		return m.effective, vfs.PathSeparatorString
+	case m.path == vfs.PathSeparatorString:
+		return m.effective, path
+	default:
+		return m.effective, path[len(m.path):]
+	}
+}
 
-		if strings.HasPrefix(path, p+vfs.PathSeparatorString) {
-			if len(mountp) < len(p) {
-				mountp = p
-				mountfs = fs
-			}
-		}
+// ValidateLink rejects links leaving a mount
+// with option NoSymlinkEscape.
+func (a *adapter) ValidateLink(path, target string) error {
+	a.fs.lock.RLock()
+	defer a.fs.lock.RUnlock()
+
+	m := a.fs.mounts.lookup(vfs.Dir(nil, path))
+	if m == nil || !m.options.NoSymlinkEscape {
+		return nil
 	}
-	if mountfs == nil {
-		return a.fs.Base(), path
+	if !isPrefix(m.path, target) {
+		return fmt.Errorf("%s: %w", path, ErrLinkOutsideMount)
+	}
+	return nil
+}
+
+// Opened counts the open files of the mount
+// responsible for the given path.
+func (a *adapter) Opened(path string) func() {
+	a.fs.lock.Lock()
+	defer a.fs.lock.Unlock()
+
+	m := a.fs.mounts.lookup(path)
+	if m == nil {
+		return nil
+	}
+	m.open++
+	return func() {
+		a.fs.lock.Lock()
+		defer a.fs.lock.Unlock()
+		m.open--
 	}
-	return mountfs, path[len(mountp):]
 }
 
 func New(root vfs.FileSystem, temp ...string) *ComposedFileSystem {
@@ -69,15 +144,19 @@ func New(root vfs.FileSystem, temp ...string) *ComposedFileSystem {
 		}
 		tempdir = vfs.Trim(nil, tempdir)
 	}
-	fs := &ComposedFileSystem{mounts: map[string]vfs.FileSystem{}, tempdir: tempdir}
+	fs := &ComposedFileSystem{tempdir: tempdir}
 	fs.MappedFileSystem = utils.NewMappedFileSystem(root, &adapter{fs})
 	return fs
 }
 
 func (c *ComposedFileSystem) Cleanup() error {
+	c.lock.RLock()
+	mounts := c.mounts.list()
+	c.lock.RUnlock()
+
 	var err error
-	for _, m := range c.mounts {
-		terr := vfs.Cleanup(m)
+	for _, m := range mounts {
+		terr := vfs.Cleanup(m.fs)
 		if terr != nil {
 			err = terr
 		}
@@ -94,6 +173,8 @@ func (c *ComposedFileSystem) Name() string {
 }
 
 func (c *ComposedFileSystem) FSTempDir() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return c.tempdir
 }
 
@@ -110,7 +191,9 @@ func (c *ComposedFileSystem) MountTempDir(path string) (string, error) {
 		vfs.Cleanup(dir)
 		return "", err
 	}
+	c.lock.Lock()
 	c.tempdir = path
+	c.lock.Unlock()
 	return path, err
 }
 
@@ -123,11 +206,26 @@ func (c *ComposedFileSystem) MountTempFileSysten(path string, fs vfs.FileSystem)
 	if err != nil {
 		return "", err
 	}
+	c.lock.Lock()
 	c.tempdir = path
+	c.lock.Unlock()
 	return path, err
 }
 
+// Mount mounts a filesystem at the given directory. Mounts
+// at or below this directory are replaced. This fails with
+// ErrBusy, if one of them is still in use.
 func (c *ComposedFileSystem) Mount(path string, fs vfs.FileSystem) error {
+	return c.MountWithOptions(path, fs, MountOptions{})
+}
+
+// MountWithOptions mounts a filesystem at the given directory
+// using the given mount options.
+func (c *ComposedFileSystem) MountWithOptions(path string, fs vfs.FileSystem, opts MountOptions) error {
+	effective, err := opts.apply(fs)
+	if err != nil {
+		return fmt.Errorf("mount failed: %s", err)
+	}
 	mountp, err := vfs.Canonical(c, path, true)
 	if err != nil {
 		return fmt.Errorf("mount failed: %s", err)
@@ -139,11 +237,118 @@ func (c *ComposedFileSystem) Mount(path string, fs vfs.FileSystem) error {
 	if !fi.IsDir() {
 		return fmt.Errorf("mount failed: mount point %s must be dir", mountp)
 	}
-	for p := range c.mounts {
-		if p == mountp || strings.Contains(p, mountp+vfs.PathSeparatorString) {
-			delete(c.mounts, p)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, m := range c.mounts.nested(mountp) {
+		if m.open > 0 {
+			return fmt.Errorf("mount failed: %s: %w", m.path, vfs.ErrBusy)
 		}
 	}
-	c.mounts[mountp] = fs
+	c.mounts.add(&mount{path: mountp, fs: fs, options: opts, effective: effective})
 	return nil
 }
+
+// Unmount removes the filesystem mounted at the given path.
+// It fails with ErrBusy, if files of the mounted filesystem
+// are still open or other filesystems are mounted below.
+// With cleanup, the unmounted filesystem is cleaned up
+// with vfs.Cleanup.
+func (c *ComposedFileSystem) Unmount(path string, cleanup ...bool) error {
+	mountp, err := vfs.Canonical(c, path, true)
+	if err != nil {
+		return vfs.NewPathError("unmount", path, err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	m := c.mounts.get(mountp)
+	if m == nil {
+		return vfs.NewPathError("unmount", path, ErrNotMounted)
+	}
+	if m.open > 0 || len(c.mounts.nested(mountp)) > 1 {
+		return vfs.NewPathError("unmount", path, vfs.ErrBusy)
+	}
+	c.mounts.remove(mountp)
+	if len(cleanup) > 0 && cleanup[0] {
+		return vfs.Cleanup(m.fs)
+	}
+	return nil
+}
+
+// Mounts provides the list of mounted filesystems
+// ordered by their mount points.
+func (c *ComposedFileSystem) Mounts() []MountPoint {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var list []MountPoint
+	for _, m := range c.mounts.list() {
+		list = append(list, MountPoint{Path: m.path, FileSystem: m.fs, Options: m.options})
+	}
+	return list
+}
+
+// Watch watches a path of the composed filesystem. Recursive
+// watches include the filesystems mounted below the path.
+func (c *ComposedFileSystem) Watch(path string, recursive bool) (vfs.Watcher, error) {
+	w, err := c.MappedFileSystem.Watch(path, recursive)
+	if err != nil || !recursive {
+		return w, err
+	}
+	dir, err := vfs.Canonical(c, path, true)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	c.lock.RLock()
+	nested := c.mounts.nested(dir)
+	c.lock.RUnlock()
+
+	watchers := []vfs.Watcher{w}
+	for _, m := range nested {
+		if m.path == dir {
+			continue
+		}
+		mw, err := c.WatchMapped(m.effective, vfs.PathSeparatorString, m.path, true)
+		if err != nil {
+			for _, w := range watchers {
+				w.Close()
+			}
+			return nil, err
+		}
+		watchers = append(watchers, mw)
+	}
+	if len(watchers) == 1 {
+		return w, nil
+	}
+	return utils.NewMappedWatcher(func(e vfs.Event) (vfs.Event, bool) { return e, true }, watchers...), nil
+}
+
+// apply provides the filesystem used to access
+// a mounted filesystem according to the options.
+func (o MountOptions) apply(fs vfs.FileSystem) (vfs.FileSystem, error) {
+	var err error
+	if o.SubDir != "" {
+		fs, err = projectionfs.New(fs, o.SubDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.ReadOnly {
+		fs = readonlyfs.New(fs)
+	}
+	if o.NoExec {
+		fs = &noexecFileSystem{fs}
+	}
+	return fs, nil
+}
+
+// isPrefix checks whether path is located at or below dir.
+func isPrefix(dir, path string) bool {
+	if dir == vfs.PathSeparatorString || dir == path {
+		return true
+	}
+	return strings.HasPrefix(path, dir+vfs.PathSeparatorString)
+}
@@ -19,10 +19,15 @@
 package composefs_test
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/mandelsoft/vfs/pkg/composefs"
 	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/osfs"
 	"github.com/mandelsoft/vfs/pkg/test"
 	"github.com/mandelsoft/vfs/pkg/vfs"
 	. "github.com/onsi/ginkgo"
@@ -58,4 +63,315 @@ var _ = Describe("compose filesystem", func() {
 			test.ExpectFolders(fs, "/tmp/d1/d2/d3/d4", nil, nil)
 		})
 	})
+	Context("link", func() {
+		var fs *composefs.ComposedFileSystem
+
+		BeforeEach(func() {
+			fs = composefs.New(memoryfs.New())
+			Expect(fs.MkdirAll("/mnt/d1", os.ModePerm)).To(Succeed())
+			Expect(fs.Mount("/mnt", memoryfs.New())).To(Succeed())
+			Expect(fs.MkdirAll("/mnt/d1", os.ModePerm)).To(Succeed())
+			test.ExpectFileCreate(fs, "/f1", []byte("content"), nil)
+			test.ExpectFileCreate(fs, "/mnt/d1/f1", []byte("mounted"), nil)
+		})
+
+		It("links in mount", func() {
+			Expect(vfs.Link(fs, "/mnt/d1/f1", "/mnt/f2")).To(Succeed())
+			test.ExpectFileContent(fs, "/mnt/f2", "mounted")
+		})
+
+		It("rejects links across mounts", func() {
+			err := vfs.Link(fs, "/f1", "/mnt/f2")
+			Expect(vfs.IsErrCrossDevice(err)).To(BeTrue())
+		})
+	})
+
+	Context("mount table", func() {
+		var fs *composefs.ComposedFileSystem
+		var mnt vfs.FileSystem
+
+		BeforeEach(func() {
+			fs = composefs.New(memoryfs.New())
+			mnt = memoryfs.New()
+			Expect(fs.MkdirAll("/mnt/d1", os.ModePerm)).To(Succeed())
+			Expect(fs.Mount("/mnt", mnt)).To(Succeed())
+			Expect(fs.MkdirAll("/mnt/sub", os.ModePerm)).To(Succeed())
+			test.ExpectFileCreate(fs, "/mnt/f1", []byte("mounted"), nil)
+		})
+
+		It("lists mounts", func() {
+			sub := memoryfs.New()
+			Expect(fs.Mount("/mnt/sub", sub)).To(Succeed())
+			Expect(fs.Mounts()).To(Equal([]composefs.MountPoint{
+				{"/mnt", mnt, composefs.MountOptions{}},
+				{"/mnt/sub", sub, composefs.MountOptions{}},
+			}))
+		})
+
+		It("unmounts", func() {
+			Expect(fs.Unmount("/mnt")).To(Succeed())
+			Expect(fs.Mounts()).To(BeEmpty())
+			test.ExpectFolders(fs, "/mnt", []string{"d1"}, nil)
+			test.ExpectFileContent(mnt, "/f1", "mounted")
+		})
+
+		It("rejects unmount of non-mount points", func() {
+			err := fs.Unmount("/mnt/sub")
+			Expect(errors.Is(err, composefs.ErrNotMounted)).To(BeTrue())
+		})
+
+		It("rejects unmount of busy mounts", func() {
+			f, err := fs.Open("/mnt/f1")
+			Expect(err).To(Succeed())
+			Expect(vfs.IsErrBusy(fs.Unmount("/mnt"))).To(BeTrue())
+			Expect(vfs.IsErrBusy(fs.Mount("/mnt", memoryfs.New()))).To(BeTrue())
+			Expect(f.Close()).To(Succeed())
+			f, err = fs.Open("/mnt/f1")
+			Expect(err).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+			Expect(fs.Unmount("/mnt")).To(Succeed())
+		})
+
+		It("rejects unmount of mounts with nested mounts", func() {
+			Expect(fs.Mount("/mnt/sub", memoryfs.New())).To(Succeed())
+			Expect(vfs.IsErrBusy(fs.Unmount("/mnt"))).To(BeTrue())
+			Expect(fs.Unmount("/mnt/sub")).To(Succeed())
+			Expect(fs.Unmount("/mnt")).To(Succeed())
+		})
+
+		It("cleans up unmounted filesystem", func() {
+			tmp, err := osfs.NewTempFileSystem()
+			Expect(err).To(Succeed())
+			Expect(fs.Mount("/mnt/sub", tmp)).To(Succeed())
+			dir := tmp.(interface{ Root() string }).Root()
+			Expect(fs.Unmount("/mnt/sub", true)).To(Succeed())
+			Expect(vfs.Exists(osfs.New(), dir)).To(BeFalse())
+		})
+	})
+
+	Context("mount options", func() {
+		var fs *composefs.ComposedFileSystem
+		var mnt vfs.FileSystem
+
+		BeforeEach(func() {
+			fs = composefs.New(memoryfs.New())
+			mnt = memoryfs.New()
+			Expect(fs.MkdirAll("/mnt", os.ModePerm)).To(Succeed())
+			Expect(mnt.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+			test.ExpectFileCreate(fs, "/f1", []byte("root"), nil)
+			test.ExpectFileCreate(mnt, "/d1/f1", []byte("mounted"), nil)
+		})
+
+		It("mounts read-only", func() {
+			opts := composefs.MountOptions{ReadOnly: true}
+			Expect(fs.MountWithOptions("/mnt", mnt, opts)).To(Succeed())
+			test.ExpectFileContent(fs, "/mnt/d1/f1", "mounted")
+			Expect(vfs.WriteFile(fs, "/mnt/d1/f2", nil, os.ModePerm)).NotTo(Succeed())
+			Expect(fs.Remove("/mnt/d1/f1")).NotTo(Succeed())
+			Expect(fs.Mounts()).To(Equal([]composefs.MountPoint{{"/mnt", mnt, opts}}))
+		})
+
+		It("binds a sub directory", func() {
+			Expect(fs.MountWithOptions("/mnt", mnt, composefs.MountOptions{SubDir: "/d1"})).To(Succeed())
+			test.ExpectFolders(fs, "/mnt", []string{"d2", "f1"}, nil)
+			test.ExpectFileContent(fs, "/mnt/f1", "mounted")
+		})
+
+		It("rejects symlinks leaving the mount", func() {
+			Expect(mnt.Symlink("/f1", "/d1/abs")).To(Succeed())
+			Expect(mnt.Symlink("../../f1", "/d1/rel")).To(Succeed())
+			Expect(mnt.Symlink("../f1", "/d1/d2/inside")).To(Succeed())
+			Expect(fs.MountWithOptions("/mnt", mnt, composefs.MountOptions{NoSymlinkEscape: true})).To(Succeed())
+
+			_, err := fs.Stat("/mnt/d1/abs")
+			Expect(errors.Is(err, composefs.ErrLinkOutsideMount)).To(BeTrue())
+			_, err = fs.Stat("/mnt/d1/rel")
+			Expect(errors.Is(err, composefs.ErrLinkOutsideMount)).To(BeTrue())
+			test.ExpectFileContent(fs, "/mnt/d1/d2/inside", "mounted")
+
+			Expect(fs.Unmount("/mnt")).To(Succeed())
+			Expect(fs.Mount("/mnt", mnt)).To(Succeed())
+			test.ExpectFileContent(fs, "/mnt/d1/abs", "root")
+		})
+
+		It("masks executable bits", func() {
+			Expect(mnt.Chmod("/d1/f1", 0o755)).To(Succeed())
+			Expect(fs.MountWithOptions("/mnt", mnt, composefs.MountOptions{NoExec: true})).To(Succeed())
+			fi, err := fs.Stat("/mnt/d1/f1")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o644)))
+			fi, err = fs.Stat("/mnt/d1")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode().Perm()).To(Equal(os.ModePerm))
+
+			list, err := vfs.ReadDir(fs, "/mnt/d1")
+			Expect(err).To(Succeed())
+			Expect(list[1].Name()).To(Equal("f1"))
+			Expect(list[1].Mode().Perm()).To(Equal(os.FileMode(0o644)))
+		})
+	})
+
+	Context("mount table", func() {
+		It("handles many mounts", func() {
+			fs := composefs.New(memoryfs.New())
+			for i := 0; i < 200; i++ {
+				dir := fmt.Sprintf("/mnt/%03d", i)
+				mnt := memoryfs.New()
+				Expect(fs.MkdirAll(dir+"/sub", os.ModePerm)).To(Succeed())
+				Expect(fs.Mount(dir, mnt)).To(Succeed())
+				Expect(vfs.WriteFile(mnt, "/file", []byte(dir), os.ModePerm)).To(Succeed())
+			}
+			Expect(len(fs.Mounts())).To(Equal(200))
+			test.ExpectFileContent(fs, "/mnt/042/file", "/mnt/042")
+			test.ExpectFileContent(fs, "/mnt/199/file", "/mnt/199")
+			Expect(fs.Unmount("/mnt/042")).To(Succeed())
+			test.ExpectFolders(fs, "/mnt/042", []string{"sub"}, nil)
+			test.ExpectFileContent(fs, "/mnt/043/file", "/mnt/043")
+		})
+
+		It("supports concurrent mounts and lookups", func() {
+			fs := composefs.New(memoryfs.New())
+			Expect(fs.MkdirAll("/mnt", os.ModePerm)).To(Succeed())
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						_, err := fs.Stat("/mnt")
+						Expect(err).To(Succeed())
+						fs.Mounts()
+					}
+				}()
+			}
+			for i := 0; i < 100; i++ {
+				Expect(fs.Mount("/mnt", memoryfs.New())).To(Succeed())
+			}
+			wg.Wait()
+		})
+	})
+
+	Context("rename", func() {
+		var fs *composefs.ComposedFileSystem
+		var mnt vfs.FileSystem
+		var mtime time.Time
+
+		BeforeEach(func() {
+			fs = composefs.New(memoryfs.New())
+			mnt = memoryfs.New()
+			mtime = time.Now().Add(-time.Hour).Truncate(time.Second)
+			Expect(fs.MkdirAll("/mnt", os.ModePerm)).To(Succeed())
+			Expect(fs.Mount("/mnt", mnt)).To(Succeed())
+			Expect(fs.MkdirAll("/src/d1", 0o750)).To(Succeed())
+			test.ExpectFileCreate(fs, "/src/d1/f1", []byte("content"), nil)
+			Expect(fs.Chmod("/src/d1/f1", 0o640)).To(Succeed())
+			Expect(fs.Symlink("d1/f1", "/src/link")).To(Succeed())
+			Expect(fs.Chtimes("/src/d1/f1", mtime, mtime)).To(Succeed())
+			Expect(fs.Chtimes("/src/d1", mtime, mtime)).To(Succeed())
+		})
+
+		It("rejects cross-mount renames by default", func() {
+			err := fs.Rename("/src", "/mnt/dst")
+			Expect(vfs.IsErrCrossDevice(err)).To(BeTrue())
+			test.ExpectFolders(fs, "/src", []string{"d1", "link"}, nil)
+		})
+
+		It("moves files across mounts", func() {
+			fs.EnableCrossRename(true)
+			Expect(fs.Rename("/src/d1/f1", "/mnt/f1")).To(Succeed())
+			test.ExpectFolders(fs, "/src/d1", nil, nil)
+			test.ExpectFolders(fs, "/mnt", []string{"f1"}, nil)
+			test.ExpectFileContent(fs, "/mnt/f1", "content")
+			fi, err := fs.Stat("/mnt/f1")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+			Expect(fi.ModTime()).To(Equal(mtime))
+		})
+
+		It("moves directory trees across mounts", func() {
+			fs.EnableCrossRename(true)
+			Expect(fs.Rename("/src", "/mnt/dst")).To(Succeed())
+			Expect(vfs.Exists(fs, "/src")).To(BeFalse())
+			test.ExpectFolders(fs, "/mnt", []string{"dst"}, nil)
+			test.ExpectFolders(fs, "/mnt/dst", []string{"d1", "link"}, nil)
+			test.ExpectFileContent(fs, "/mnt/dst/link", "content")
+			Expect(fs.Readlink("/mnt/dst/link")).To(Equal("d1/f1"))
+			fi, err := fs.Stat("/mnt/dst/d1")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o750)))
+			Expect(fi.ModTime()).To(Equal(mtime))
+		})
+
+		It("keeps source on failure", func() {
+			fs.EnableCrossRename(true)
+			Expect(fs.Unmount("/mnt")).To(Succeed())
+			Expect(fs.MountWithOptions("/mnt", mnt, composefs.MountOptions{ReadOnly: true})).To(Succeed())
+			Expect(fs.Rename("/src", "/mnt/dst")).NotTo(Succeed())
+			test.ExpectFolders(fs, "/src", []string{"d1", "link"}, nil)
+			test.ExpectFolders(fs, "/mnt", nil, nil)
+		})
+
+		It("writes files atomically in mounts", func() {
+			test.ExpectFileCreate(fs, "/mnt/f1", []byte("old"), nil)
+			Expect(fs.Symlink("/mnt/f1", "/src/mntlink")).To(Succeed())
+			Expect(vfs.WriteFileAtomic(fs, "/src/mntlink", []byte("new"), os.ModePerm)).To(Succeed())
+			test.ExpectFileContent(mnt, "/f1", "new")
+			test.ExpectFolders(fs, "/mnt", []string{"f1"}, nil)
+		})
+
+		It("rejects moving a directory into itself", func() {
+			fs.EnableCrossRename(true)
+			Expect(fs.MkdirAll("/src/mnt", os.ModePerm)).To(Succeed())
+			Expect(fs.Mount("/src/mnt", memoryfs.New())).To(Succeed())
+			Expect(fs.Rename("/src", "/src/mnt/dst")).NotTo(Succeed())
+			test.ExpectFolders(fs, "/src/mnt", nil, nil)
+		})
+	})
+
+	Context("watch", func() {
+		var fs *composefs.ComposedFileSystem
+		var mnt vfs.FileSystem
+
+		BeforeEach(func() {
+			fs = composefs.New(memoryfs.New())
+			mnt = memoryfs.New()
+			Expect(fs.MkdirAll("/mnt/shadowed", os.ModePerm)).To(Succeed())
+			Expect(fs.Mount("/mnt", mnt)).To(Succeed())
+			Expect(fs.MkdirAll("/mnt/d1/sub", os.ModePerm)).To(Succeed())
+		})
+
+		It("translates paths of mounted filesystems", func() {
+			w, err := fs.Watch("/mnt/d1", false)
+			Expect(err).To(Succeed())
+			defer w.Close()
+			Expect(vfs.WriteFile(mnt, "/d1/f1", nil, os.ModePerm)).To(Succeed())
+			test.ExpectEvent(w, "/mnt/d1/f1", vfs.EventCreate)
+		})
+
+		It("includes nested mounts in recursive watches", func() {
+			sub := memoryfs.New()
+			Expect(fs.Mount("/mnt/d1/sub", sub)).To(Succeed())
+			w, err := fs.Watch("/", true)
+			Expect(err).To(Succeed())
+			defer w.Close()
+
+			Expect(vfs.WriteFile(fs, "/f1", nil, os.ModePerm)).To(Succeed())
+			test.ExpectEvent(w, "/f1", vfs.EventCreate)
+			Expect(vfs.WriteFile(fs, "/mnt/d1/f1", nil, os.ModePerm)).To(Succeed())
+			test.ExpectEvent(w, "/mnt/d1/f1", vfs.EventCreate)
+			Expect(vfs.WriteFile(fs, "/mnt/d1/sub/f1", nil, os.ModePerm)).To(Succeed())
+			test.ExpectEvent(w, "/mnt/d1/sub/f1", vfs.EventCreate)
+		})
+
+		It("drops events of shadowed entries", func() {
+			w, err := fs.Watch("/", true)
+			Expect(err).To(Succeed())
+			defer w.Close()
+			Expect(vfs.WriteFile(fs.Base(), "/mnt/shadowed/f1", nil, os.ModePerm)).To(Succeed())
+			test.ExpectNoEvent(w)
+		})
+	})
 })
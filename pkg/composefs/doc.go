@@ -18,4 +18,11 @@
 
 // Package composefs provides a virtual filesystem implementation for
 // orchestrating multiple other virtual filesystems to a single one.
+// Filesystems are mounted into a root filesystem (Mount) and can be
+// removed again (Unmount), as long as no files of them are open.
+// MountWithOptions supports Linux-like mount options, like read-only
+// mounts, binding a sub directory, rejecting symbolic links leaving
+// the mount and masking executable bits (noexec).
+// Renames across mounts fail with vfs.ErrCrossDevice, unless they are
+// enabled with EnableCrossRename, which moves entries by copy and delete.
 package composefs
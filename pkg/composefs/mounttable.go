@@ -0,0 +1,142 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package composefs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// mountTable is a trie of mounts indexed by the
+// path components of their mount points.
+type mountTable struct {
+	root mountNode
+}
+
+type mountNode struct {
+	children map[string]*mountNode
+	mount    *mount
+}
+
+func components(path string) []string {
+	var list []string
+	for _, c := range strings.Split(path, vfs.PathSeparatorString) {
+		if c != "" {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// get provides the mount for the given mount point.
+func (t *mountTable) get(path string) *mount {
+	n := &t.root
+	for _, c := range components(path) {
+		n = n.children[c]
+		if n == nil {
+			return nil
+		}
+	}
+	return n.mount
+}
+
+// lookup provides the mount responsible for the given path,
+// which is the mount with the longest mount point at or above
+// the path.
+func (t *mountTable) lookup(path string) *mount {
+	n := &t.root
+	m := n.mount
+	for _, c := range components(path) {
+		n = n.children[c]
+		if n == nil {
+			break
+		}
+		if n.mount != nil {
+			m = n.mount
+		}
+	}
+	return m
+}
+
+// add adds a mount replacing all mounts at or below its mount point.
+func (t *mountTable) add(m *mount) {
+	n := &t.root
+	for _, c := range components(m.path) {
+		next := n.children[c]
+		if next == nil {
+			if n.children == nil {
+				n.children = map[string]*mountNode{}
+			}
+			next = &mountNode{}
+			n.children[c] = next
+		}
+		n = next
+	}
+	n.children = nil
+	n.mount = m
+}
+
+// remove removes the mount for the given mount point
+// and prunes unused nodes.
+func (t *mountTable) remove(path string) {
+	t.root.remove(components(path))
+}
+
+func (n *mountNode) remove(path []string) bool {
+	if len(path) == 0 {
+		n.mount = nil
+	} else if c := n.children[path[0]]; c != nil && c.remove(path[1:]) {
+		delete(n.children, path[0])
+	}
+	return n.mount == nil && len(n.children) == 0
+}
+
+// nested provides the mounts at or below the given path.
+func (t *mountTable) nested(path string) []*mount {
+	n := &t.root
+	for _, c := range components(path) {
+		n = n.children[c]
+		if n == nil {
+			return nil
+		}
+	}
+	return n.list(nil)
+}
+
+// list provides all mounts ordered by their mount points.
+func (t *mountTable) list() []*mount {
+	return t.root.list(nil)
+}
+
+func (n *mountNode) list(list []*mount) []*mount {
+	if n.mount != nil {
+		list = append(list, n.mount)
+	}
+	names := make([]string, 0, len(n.children))
+	for c := range n.children {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	for _, c := range names {
+		list = n.children[c].list(list)
+	}
+	return list
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package composefs
+
+import (
+	iofs "io/fs"
+	"os"
+
+	"github.com/mandelsoft/vfs/pkg/utils"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// execMask are the executable bits masked by a noexec mount.
+const execMask = os.FileMode(0o111)
+
+// noexecFileSystem reports all non-directory entries
+// of a filesystem without executable bits.
+type noexecFileSystem struct {
+	vfs.FileSystem
+}
+
+var _ vfs.OwnershipFileSystem = &noexecFileSystem{}
+var _ vfs.LinkFileSystem = &noexecFileSystem{}
+var _ vfs.XattrFileSystem = &noexecFileSystem{}
+var _ vfs.WatchableFileSystem = &noexecFileSystem{}
+
+func noexecInfo(fi os.FileInfo) os.FileInfo {
+	if fi == nil || fi.IsDir() || fi.Mode()&execMask == 0 {
+		return fi
+	}
+	return &noexecFileInfo{fi}
+}
+
+type noexecFileInfo struct {
+	os.FileInfo
+}
+
+func (fi *noexecFileInfo) Mode() os.FileMode {
+	return fi.FileInfo.Mode() &^ execMask
+}
+
+func (n *noexecFileSystem) Stat(name string) (os.FileInfo, error) {
+	fi, err := n.FileSystem.Stat(name)
+	return noexecInfo(fi), err
+}
+
+func (n *noexecFileSystem) Lstat(name string) (os.FileInfo, error) {
+	fi, err := n.FileSystem.Lstat(name)
+	return noexecInfo(fi), err
+}
+
+func (n *noexecFileSystem) Create(name string) (vfs.File, error) {
+	return noexecFile(n.FileSystem.Create(name))
+}
+
+func (n *noexecFileSystem) Open(name string) (vfs.File, error) {
+	return noexecFile(n.FileSystem.Open(name))
+}
+
+func (n *noexecFileSystem) OpenFile(name string, flags int, perm os.FileMode) (vfs.File, error) {
+	return noexecFile(n.FileSystem.OpenFile(name, flags, perm))
+}
+
+func (n *noexecFileSystem) Chown(name string, uid, gid int) error {
+	return vfs.Chown(n.FileSystem, name, uid, gid)
+}
+
+func (n *noexecFileSystem) Lchown(name string, uid, gid int) error {
+	return vfs.Lchown(n.FileSystem, name, uid, gid)
+}
+
+func (n *noexecFileSystem) Link(oldname, newname string) error {
+	return vfs.Link(n.FileSystem, oldname, newname)
+}
+
+func (n *noexecFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	return vfs.Getxattr(n.FileSystem, name, attr)
+}
+
+func (n *noexecFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	return vfs.Setxattr(n.FileSystem, name, attr, data, flags)
+}
+
+func (n *noexecFileSystem) Listxattr(name string) ([]string, error) {
+	return vfs.Listxattr(n.FileSystem, name)
+}
+
+func (n *noexecFileSystem) Removexattr(name, attr string) error {
+	return vfs.Removexattr(n.FileSystem, name, attr)
+}
+
+func (n *noexecFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	return vfs.Lgetxattr(n.FileSystem, name, attr)
+}
+
+func (n *noexecFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return vfs.Lsetxattr(n.FileSystem, name, attr, data, flags)
+}
+
+func (n *noexecFileSystem) Llistxattr(name string) ([]string, error) {
+	return vfs.Llistxattr(n.FileSystem, name)
+}
+
+func (n *noexecFileSystem) Lremovexattr(name, attr string) error {
+	return vfs.Lremovexattr(n.FileSystem, name, attr)
+}
+
+func (n *noexecFileSystem) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	return vfs.Watch(n.FileSystem, name, recursive)
+}
+
+func (n *noexecFileSystem) Cleanup() error {
+	return vfs.Cleanup(n.FileSystem)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type noexecFileHandle struct {
+	vfs.File
+}
+
+func noexecFile(f vfs.File, err error) (vfs.File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &noexecFileHandle{f}, nil
+}
+
+func (f *noexecFileHandle) Lock(t vfs.LockType, wait bool) error {
+	return vfs.Lock(f.File, t, wait)
+}
+
+func (f *noexecFileHandle) Unlock() error {
+	return vfs.Unlock(f.File)
+}
+
+func (f *noexecFileHandle) LockRange(offset, length int64, t vfs.LockType, wait bool) error {
+	return vfs.LockRange(f.File, offset, length, t, wait)
+}
+
+func (f *noexecFileHandle) UnlockRange(offset, length int64) error {
+	return vfs.UnlockRange(f.File, offset, length)
+}
+
+func (f *noexecFileHandle) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	return noexecInfo(fi), err
+}
+
+func (f *noexecFileHandle) Readdir(count int) ([]os.FileInfo, error) {
+	list, err := f.File.Readdir(count)
+	for i, fi := range list {
+		list[i] = noexecInfo(fi)
+	}
+	return list, err
+}
+
+func (f *noexecFileHandle) ReadDir(count int) ([]vfs.DirEntry, error) {
+	list, err := f.File.ReadDir(count)
+	for i, e := range list {
+		if e.Type().IsDir() {
+			continue
+		}
+		if fi, ierr := e.Info(); ierr == nil {
+			if nfi := noexecInfo(fi); nfi != fi {
+				list[i] = iofs.FileInfoToDirEntry(nfi)
+			}
+		}
+	}
+	return list, err
+}
+
+func (f *noexecFileHandle) OSFile() *os.File {
+	return utils.OSFile(f.File)
+}
@@ -139,6 +139,16 @@ func (w *WorkingDirectoryFileSystem) OpenFile(name string, flag int, perm os.Fil
 	return w.base.OpenFile(abs, flag, perm)
 }
 
+// Watch watches a path of the base filesystem. Events are
+// reported with absolute paths.
+func (w *WorkingDirectoryFileSystem) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Watch(w.base, abs, recursive)
+}
+
 func (w *WorkingDirectoryFileSystem) Remove(name string) error {
 	abs, err := w.realPath(name)
 	if err != nil {
@@ -191,6 +201,86 @@ func (w *WorkingDirectoryFileSystem) Chtimes(name string, atime time.Time, mtime
 	return w.base.Chtimes(abs, atime, mtime)
 }
 
+func (w *WorkingDirectoryFileSystem) Chown(name string, uid, gid int) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Chown(w.base, abs, uid, gid)
+}
+
+func (w *WorkingDirectoryFileSystem) Lchown(name string, uid, gid int) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Lchown(w.base, abs, uid, gid)
+}
+
+func (w *WorkingDirectoryFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Getxattr(w.base, abs, attr)
+}
+
+func (w *WorkingDirectoryFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Setxattr(w.base, abs, attr, data, flags)
+}
+
+func (w *WorkingDirectoryFileSystem) Listxattr(name string) ([]string, error) {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Listxattr(w.base, abs)
+}
+
+func (w *WorkingDirectoryFileSystem) Removexattr(name, attr string) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Removexattr(w.base, abs, attr)
+}
+
+func (w *WorkingDirectoryFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Lgetxattr(w.base, abs, attr)
+}
+
+func (w *WorkingDirectoryFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Lsetxattr(w.base, abs, attr, data, flags)
+}
+
+func (w *WorkingDirectoryFileSystem) Llistxattr(name string) ([]string, error) {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Llistxattr(w.base, abs)
+}
+
+func (w *WorkingDirectoryFileSystem) Lremovexattr(name, attr string) error {
+	abs, err := w.realPath(name)
+	if err != nil {
+		return err
+	}
+	return vfs.Lremovexattr(w.base, abs, attr)
+}
+
 func (w *WorkingDirectoryFileSystem) Lstat(name string) (os.FileInfo, error) {
 	abs, err := w.realPath(name)
 	if err != nil {
@@ -207,6 +297,18 @@ func (w *WorkingDirectoryFileSystem) Symlink(oldname, newname string) error {
 	return w.base.Symlink(oldname, abs)
 }
 
+func (w *WorkingDirectoryFileSystem) Link(oldname, newname string) error {
+	absnew, err := w.realPath(newname)
+	if err != nil {
+		return err
+	}
+	absold, err := w.realPath(oldname)
+	if err != nil {
+		return err
+	}
+	return vfs.Link(w.base, absold, absnew)
+}
+
 func (w *WorkingDirectoryFileSystem) Readlink(name string) (string, error) {
 	abs, err := w.realPath(name)
 	if err != nil {
@@ -0,0 +1,378 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// ChangeKind describes the kind of a change recorded in a layer.
+type ChangeKind string
+
+const (
+	// ChangeAdd describes an entry not present in the read-only layers.
+	ChangeAdd ChangeKind = "add"
+	// ChangeModify describes an entry replacing or updating an entry
+	// of the read-only layers.
+	ChangeModify ChangeKind = "modify"
+	// ChangeDelete describes an entry deleted from the read-only layers.
+	ChangeDelete ChangeKind = "delete"
+)
+
+// Change describes a modification recorded in the writable layer.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	// Mode is the file mode of the changed entry. For deletions
+	// it is the mode of the deleted entry.
+	Mode os.FileMode
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Path)
+}
+
+// Commit applies the modifications collected in the writable layer
+// (files, directories, symbolic links, deletions and opaque directories)
+// to the base filesystem, which is the topmost read-only layer,
+// and clears the writable layer afterwards. If further read-only layers
+// are used, deletions are kept as whiteouts in the base filesystem.
+// With dryrun, nothing is changed and only the list of changes
+// is returned.
+func (l *LayerFileSystem) Commit(dryrun bool) ([]Change, error) {
+	if len(l.layers) < 2 {
+		return nil, errors.New("no base layer to commit to")
+	}
+	c := &committer{l: l, base: l.layers[1], whiteouts: len(l.layers) > 2, apply: !dryrun}
+	err := c.commit(vfs.PathSeparatorString)
+	if err != nil || dryrun {
+		return c.changes, err
+	}
+	return c.changes, l.clear()
+}
+
+// Discard drops all modifications collected in the writable layer.
+// With dryrun, nothing is changed and only the list of discarded
+// changes is returned.
+func (l *LayerFileSystem) Discard(dryrun bool) ([]Change, error) {
+	c := &committer{l: l}
+	err := c.commit(vfs.PathSeparatorString)
+	if err != nil || dryrun {
+		return c.changes, err
+	}
+	return c.changes, l.clear()
+}
+
+// clear removes all entries from the writable layer.
+func (l *LayerFileSystem) clear() error {
+	names, err := readDirNames(l.layer, vfs.PathSeparatorString)
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		if err := l.layer.RemoveAll(vfs.Join(l.layer, vfs.PathSeparatorString, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDirNames(fs vfs.FileSystem, path string) ([]string, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(0)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// committer determines the changes of the writable layer
+// and optionally applies them to the base filesystem.
+type committer struct {
+	l         *LayerFileSystem
+	base      vfs.FileSystem
+	whiteouts bool
+	apply     bool
+	changes   []Change
+}
+
+func (c *committer) add(kind ChangeKind, path string, mode os.FileMode) {
+	c.changes = append(c.changes, Change{Kind: kind, Path: path, Mode: mode})
+}
+
+// commit handles the content of a directory of the writable layer.
+func (c *committer) commit(dir string) error {
+	layer := c.l.layer
+	names, err := readDirNames(layer, dir)
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]struct{}{}
+	opaque := false
+	for _, n := range names {
+		switch {
+		case n == opaque_del:
+			opaque = true
+		case strings.HasPrefix(n, del_prefix):
+		default:
+			entries[n] = struct{}{}
+		}
+	}
+
+	// deletions
+	if opaque {
+		visible, err := c.lowerNames(dir)
+		if err != nil {
+			return err
+		}
+		for _, n := range visible {
+			if _, ok := entries[n]; !ok {
+				if err := c.delete(vfs.Join(layer, dir, n), false); err != nil {
+					return err
+				}
+			}
+		}
+		if c.apply && c.whiteouts {
+			if err := vfs.Touch(c.base, vfs.Join(c.base, dir, opaque_del), os.ModePerm); err != nil {
+				return err
+			}
+		}
+	}
+	for _, n := range names {
+		if strings.HasPrefix(n, del_prefix) && n != opaque_del {
+			if err := c.delete(vfs.Join(layer, dir, n[len(del_prefix):]), !opaque); err != nil {
+				return err
+			}
+		}
+	}
+
+	// additions and modifications
+	for _, n := range names {
+		if _, ok := entries[n]; !ok {
+			continue
+		}
+		if err := c.update(vfs.Join(layer, dir, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lowerNames provides the names of the entries of a directory
+// visible in the read-only layers.
+func (c *committer) lowerNames(dir string) ([]string, error) {
+	lower := c.l.lowerUnion()
+	if lower == nil {
+		return nil, nil
+	}
+	fi, err := lower.Lstat(dir)
+	if err != nil || !fi.IsDir() {
+		if err == nil || vfs.IsErrNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return readDirNames(lower, dir)
+}
+
+// lowerInfo provides the file info of an entry visible
+// in the read-only layers.
+func (c *committer) lowerInfo(path string) (os.FileInfo, error) {
+	lower := c.l.lowerUnion()
+	if lower == nil {
+		return nil, nil
+	}
+	fi, err := lower.Lstat(path)
+	if err != nil {
+		if vfs.IsErrNotExist(err) || vfs.IsErrNotDir(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return fi, nil
+}
+
+// delete deletes an entry from the base filesystem. If required,
+// a whiteout is created to hide the entry in lower layers.
+func (c *committer) delete(path string, whiteout bool) error {
+	fi, err := c.lowerInfo(path)
+	if err != nil || fi == nil {
+		return err
+	}
+	c.add(ChangeDelete, path, fi.Mode())
+	if !c.apply {
+		return nil
+	}
+	if err := removeAll(c.base, path); err != nil {
+		return err
+	}
+	if c.whiteouts && whiteout {
+		return vfs.Touch(c.base, markerFor(path), os.ModePerm)
+	}
+	return nil
+}
+
+func (c *committer) update(path string) error {
+	layer := c.l.layer
+	fi, err := layer.Lstat(path)
+	if err != nil {
+		return err
+	}
+	old, err := c.lowerInfo(path)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		if old == nil || !old.IsDir() {
+			c.add(ChangeAdd, path, fi.Mode())
+		} else if metadataChanged(old, fi) {
+			c.add(ChangeModify, path, fi.Mode())
+		}
+		if c.apply {
+			if err := c.prepare(path, true); err != nil {
+				return err
+			}
+			if ok, err := vfs.DirExists(c.base, path); !ok || err != nil {
+				if err := c.base.Mkdir(path, fi.Mode()&os.ModePerm); err != nil {
+					return err
+				}
+			}
+		}
+		if err := c.commit(path); err != nil {
+			return err
+		}
+		if c.apply {
+			return c.metadata(path, fi)
+		}
+		return nil
+	}
+
+	if old == nil {
+		c.add(ChangeAdd, path, fi.Mode())
+	} else {
+		c.add(ChangeModify, path, fi.Mode())
+	}
+	if !c.apply {
+		return nil
+	}
+	if err := c.prepare(path, false); err != nil {
+		return err
+	}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := layer.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if err := c.base.Symlink(link, path); err != nil {
+			return err
+		}
+		return c.metadata(path, fi)
+	case fi.Mode().IsRegular():
+		if err := vfs.CopyFile(layer, path, c.base, path, vfs.CopyOwnership); err != nil {
+			return err
+		}
+		return c.base.Chtimes(path, time.Now(), fi.ModTime())
+	default:
+		return fmt.Errorf("%s: file type not supported", path)
+	}
+}
+
+// prepare removes an entry of the base filesystem conflicting
+// with an entry to be committed. Existing directories are kept
+// for directories.
+func (c *committer) prepare(path string, dir bool) error {
+	fi, err := c.base.Lstat(path)
+	if err != nil {
+		if vfs.IsErrNotExist(err) {
+			return removeMarker(c.base, path)
+		}
+		return err
+	}
+	if !dir || !fi.IsDir() {
+		if err := c.base.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return removeMarker(c.base, path)
+}
+
+// removeAll removes an entry, which might not exist.
+func removeAll(fs vfs.FileSystem, path string) error {
+	err := fs.RemoveAll(path)
+	if err != nil && vfs.IsErrNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// removeMarker removes a whiteout for the given path.
+func removeMarker(fs vfs.FileSystem, path string) error {
+	err := fs.Remove(markerFor(path))
+	if err != nil && vfs.IsErrNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// metadataChanged reports whether the metadata applied by
+// metadata differs for two directories.
+func metadataChanged(old, fi os.FileInfo) bool {
+	if old.Mode() != fi.Mode() || !old.ModTime().Equal(fi.ModTime()) {
+		return true
+	}
+	ouid, ogid, ook := vfs.Owner(old)
+	uid, gid, ok := vfs.Owner(fi)
+	return ok && uid >= 0 && gid >= 0 && (!ook || ouid != uid || ogid != gid)
+}
+
+// metadata applies the metadata of a layer entry
+// to the base filesystem.
+func (c *committer) metadata(path string, fi os.FileInfo) error {
+	layer := c.l.layer
+	if err := vfs.CopyXattrs(layer, path, c.base, path); err != nil && !vfs.IsErrPermission(err) && !vfs.IsErrNotSupported(err) {
+		return err
+	}
+	if uid, gid, ok := vfs.Owner(fi); ok && uid >= 0 && gid >= 0 {
+		if err := vfs.Lchown(c.base, path, uid, gid); err != nil && !vfs.IsErrNotSupported(err) {
+			return err
+		}
+	}
+	if fi.IsDir() {
+		if err := c.base.Chmod(path, fi.Mode()); err != nil {
+			return err
+		}
+		return c.base.Chtimes(path, time.Now(), fi.ModTime())
+	}
+	return nil
+}
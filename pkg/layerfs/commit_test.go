@@ -0,0 +1,144 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("commit", func() {
+	var fs *LayerFileSystem
+	var layer vfs.FileSystem
+	var base vfs.FileSystem
+
+	changes := []string{
+		"modify /base",
+		"delete /base/basefile",
+		"modify /base/d1",
+		"delete /base/d1/basefile",
+		"add /base/d1/link",
+		"add /base/d1/newfile",
+		"modify /base/d1/otherfile",
+		"add /new",
+		"add /new/file",
+	}
+
+	list := func(changes []Change, err error) []string {
+		Expect(err).To(Succeed())
+		result := []string{}
+		for _, c := range changes {
+			result = append(result, c.String())
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var v vfs.FileSystem
+		v, layer, base = NewTestEnv()
+		fs = v.(*LayerFileSystem)
+
+		Expect(fs.Remove("/base/basefile")).To(Succeed())
+		Expect(fs.RemoveAll("/base/d1")).To(Succeed())
+		Expect(fs.Mkdir("/base/d1", os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(fs, "/base/d1/otherfile", []byte("other"), os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(fs, "/base/d1/newfile", []byte("new"), os.ModePerm)).To(Succeed())
+		Expect(fs.Symlink("newfile", "/base/d1/link")).To(Succeed())
+		Expect(fs.MkdirAll("/new", os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(fs, "/new/file", DefaultContent, os.ModePerm)).To(Succeed())
+	})
+
+	checkView := func(fs vfs.FileSystem) {
+		ExpectFolders(fs, "/", []string{"base", "new"}, nil)
+		ExpectFolders(fs, "/base", []string{"d1"}, nil)
+		ExpectFolders(fs, "/base/d1", []string{"link", "newfile", "otherfile"}, nil)
+		ExpectFileContent(fs, "/base/d1/otherfile", "other")
+		ExpectFileContent(fs, "/base/d1/link", "new")
+		ExpectFileContent(fs, "/new/file", string(DefaultContent))
+	}
+
+	It("reports changes in dry-run mode", func() {
+		result, err := fs.Commit(true)
+		Expect(list(result, err)).To(Equal(changes))
+		Expect(result[4].Mode & os.ModeType).To(Equal(os.ModeSymlink))
+		Expect(result[7].Mode & os.ModeType).To(Equal(os.ModeDir))
+		ExpectFolders(base, "/base/d1", []string{"basefile", "otherfile"}, nil)
+		checkView(fs)
+	})
+
+	It("commits changes", func() {
+		Expect(list(fs.Commit(false))).To(Equal(changes))
+		ExpectFolders(layer, "/", nil, nil)
+		checkView(base)
+		checkView(fs)
+		Expect(list(fs.Commit(true))).To(BeEmpty())
+	})
+
+	It("discards changes", func() {
+		Expect(list(fs.Discard(true))).To(Equal(changes))
+		checkView(fs)
+		Expect(list(fs.Discard(false))).To(Equal(changes))
+		ExpectFolders(layer, "/", nil, nil)
+		ExpectFolders(fs, "/base/d1", []string{"basefile", "otherfile"}, nil)
+		ExpectFileContent(fs, "/base/d1/otherfile", string(DefaultContent))
+	})
+
+	It("keeps whiteouts for lower layers", func() {
+		bottom := memoryfs.New()
+		Expect(bottom.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(bottom, "/d1/f1", DefaultContent, os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(bottom, "/d1/d2/f2", DefaultContent, os.ModePerm)).To(Succeed())
+		top := memoryfs.New()
+		fs := NewUnion(memoryfs.New(), top, bottom)
+
+		Expect(fs.Remove("/d1/f1")).To(Succeed())
+		Expect(fs.RemoveAll("/d1/d2")).To(Succeed())
+		Expect(fs.Mkdir("/d1/d2", os.ModePerm)).To(Succeed())
+		Expect(list(fs.Commit(false))).To(Equal([]string{
+			"modify /d1",
+			"delete /d1/f1",
+			"modify /d1/d2",
+			"delete /d1/d2/f2",
+		}))
+		ExpectFolders(top, "/d1", []string{".wh.f1", "d2"}, nil)
+		ExpectFolders(top, "/d1/d2", []string{".wh..wh..opq"}, nil)
+		ExpectFolders(fs, "/d1", []string{"d2"}, nil)
+		ExpectFolders(fs, "/d1/d2", nil, nil)
+		ExpectFolders(bottom, "/d1", []string{"d2", "f1"}, nil)
+	})
+	It("reports metadata changes of directories", func() {
+		bottom := memoryfs.New()
+		Expect(bottom.MkdirAll("/d1/d2", 0o755)).To(Succeed())
+		fs := NewUnion(memoryfs.New(), bottom)
+
+		Expect(fs.Chmod("/d1/d2", 0o700)).To(Succeed())
+		Expect(list(fs.Commit(true))).To(Equal([]string{"modify /d1", "modify /d1/d2"}))
+		Expect(list(fs.Commit(false))).To(Equal([]string{"modify /d1", "modify /d1/d2"}))
+		fi, err := bottom.Stat("/d1/d2")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+		Expect(list(fs.Commit(true))).To(BeEmpty())
+	})
+})
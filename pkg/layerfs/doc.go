@@ -19,4 +19,11 @@
 // Package layerfs provides a virtual filesystem supporting a filesystem layer
 // on top of a base filesystem, that is used to keep track of all changes
 // done to the filesystem. Thereby the root filesystem is not changed.
+// With NewUnion a writable layer can be put on top of a stack of multiple
+// read-only layers, which are resolved in a single pass.
+// Deletions are recorded in the layer with OCI style whiteout files, which
+// allows to export a layer as OCI layer tar archive (Export) and to
+// apply such an archive as layer on top of a base filesystem (Import).
+// The changes kept in the layer can be merged into the base filesystem
+// (Commit) or dropped (Discard).
 package layerfs
@@ -45,61 +45,63 @@ func (f *file) OSFile() *os.File {
 	return utils.OSFile(f.File)
 }
 
+func (f *file) Lock(t vfs.LockType, wait bool) error {
+	return vfs.Lock(f.File, t, wait)
+}
+
+func (f *file) Unlock() error {
+	return vfs.Unlock(f.File)
+}
+
+func (f *file) LockRange(offset, length int64, t vfs.LockType, wait bool) error {
+	return vfs.LockRange(f.File, offset, length, t, wait)
+}
+
+func (f *file) UnlockRange(offset, length int64) error {
+	return vfs.UnlockRange(f.File, offset, length)
+}
+
 func (f *file) Readdir(count int) ([]os.FileInfo, error) {
 	var outLength int64
 
 	if f.files == nil {
-		list, err := f.File.Readdir(0)
-		if err != nil {
-			return nil, err
-		}
 		files := []os.FileInfo{}
 		deleted := map[string]struct{}{}
 		found := map[string]struct{}{}
-		forward := true
-		for _, e := range list {
-			n := e.Name()
-			if n == opaque_del {
-				forward = false
-			} else {
-				if strings.HasPrefix(n, del_prefix) {
-					deleted[n[len(del_prefix):]] = struct{}{}
-				} else {
-					found[n] = struct{}{}
-					files = append(files, e)
-				}
-			}
-		}
-		if forward {
-			file, err := f.info.base.Open(f.info.path)
+		for i := f.info.index; i < f.info.limit; i++ {
+			list, ok, err := f.readLayer(i)
 			if err != nil {
-				if !vfs.IsErrNotExist(err) {
-					return nil, err
-				}
+				return nil, err
 			}
-			if file != nil {
-				defer file.Close()
-				list, err := file.Readdir(0)
-				if err != nil {
-					return nil, err
-				}
-				for _, e := range list {
-					n := e.Name()
-					if n == opaque_del {
-						continue
-					}
+			if !ok {
+				break
+			}
+			opaque := false
+			whiteouts := []string{}
+			for _, e := range list {
+				n := e.Name()
+				switch {
+				case n == opaque_del:
+					opaque = true
+				case strings.HasPrefix(n, del_prefix):
+					whiteouts = append(whiteouts, n[len(del_prefix):])
+				default:
 					if _, ok := deleted[n]; ok {
 						continue
 					}
 					if _, ok := found[n]; ok {
 						continue
 					}
-					if strings.HasPrefix(n, del_prefix) {
-						continue
-					}
+					found[n] = struct{}{}
 					files = append(files, e)
 				}
 			}
+			if opaque {
+				break
+			}
+			for _, n := range whiteouts {
+				deleted[n] = struct{}{}
+			}
 		}
 
 		sort.Sort(utils.FilesSorter(files))
@@ -124,6 +126,34 @@ func (f *file) Readdir(count int) ([]os.FileInfo, error) {
 	return files, err
 }
 
+// readLayer reads the directory content of the given layer.
+// It reports false, if the layer provides a non-directory
+// entry hiding the directory content of all lower layers.
+func (f *file) readLayer(i int) ([]os.FileInfo, bool, error) {
+	if i == f.info.index {
+		list, err := f.File.Readdir(0)
+		return list, err == nil, err
+	}
+	fs := f.info.layers[i]
+	fi, err := fs.Lstat(f.info.path)
+	if err != nil {
+		if vfs.IsErrNotExist(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	if !fi.IsDir() {
+		return nil, false, nil
+	}
+	file, err := fs.Open(f.info.path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	list, err := file.Readdir(0)
+	return list, err == nil, err
+}
+
 func (f *file) Readdirnames(n int) (names []string, err error) {
 	fi, err := f.Readdir(n)
 	names = make([]string, len(fi))
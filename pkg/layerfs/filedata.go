@@ -29,11 +29,19 @@ import (
 const opaque_del = ".wh..wh..opq"
 const del_prefix = ".wh."
 
+// fileData describes a filesystem entry of the layer stack.
+// It is provided by the layer with the given index. Layer 0
+// is the writable layer, all other layers are read-only.
+// The content of a directory is provided by the layers
+// from index up to (excluding) limit. Lower layers are hidden
+// by whiteouts for the directory or one of its parents.
 type fileData struct {
-	fs   vfs.FileSystem
-	base vfs.FileSystem
-	path string
-	fi   os.FileInfo
+	layers []vfs.FileSystem
+	index  int
+	limit  int
+	fs     vfs.FileSystem
+	path   string
+	fi     os.FileInfo
 }
 
 func asFileData(data utils.FileDataDirAccess) *fileData {
@@ -43,8 +51,13 @@ func asFileData(data utils.FileDataDirAccess) *fileData {
 	return data.(*fileData)
 }
 
-func newFileData(fs vfs.FileSystem, base vfs.FileSystem, path string, fi os.FileInfo) *fileData {
-	return &fileData{fs: fs, base: base, path: path, fi: fi}
+func newFileData(layers []vfs.FileSystem, index int, path string, fi os.FileInfo) *fileData {
+	return &fileData{layers: layers, index: index, limit: len(layers), fs: layers[index], path: path, fi: fi}
+}
+
+// inLayer reports whether the entry is provided by the writable layer.
+func (f *fileData) inLayer() bool {
+	return f.index == 0
 }
 
 func (f *fileData) Lock() {
@@ -53,45 +66,64 @@ func (f *fileData) Lock() {
 func (f *fileData) Unlock() {
 }
 
+// GetEntry resolves a directory entry in a single pass over the layer
+// stack, starting with the layer providing the directory.
+// An entry is hidden by a whiteout file (.wh.<name>) in a higher layer.
+// An opaque whiteout (.wh..wh..opq) or a non-directory in a layer hides
+// the directory content of all lower layers.
+// For directories the remaining layers are checked, also, to
+// determine the layers hidden for the directory content.
 func (f *fileData) GetEntry(name string) (utils.FileDataDirAccess, error) {
 	if strings.HasPrefix(name, del_prefix) || name == opaque_del {
 		return nil, vfs.ErrNotExist
 	}
-	file, err := f.fs.Open(f.path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	names, err := file.Readdirnames(0)
-	if err != nil {
+	if !f.IsDir() {
 		return nil, vfs.ErrNotDir
 	}
 
-	del := del_prefix + name
+	var found *fileData
 	fpath := vfs.Join(f.fs, f.path, name)
-	forward := f.base != nil
-	for _, n := range names {
-		switch n {
-		case name:
-			fi, err := f.fs.Lstat(fpath)
+	limit := f.limit
+	for i := f.index; i < limit; i++ {
+		fs := f.layers[i]
+		if i > f.index {
+			fi, err := fs.Lstat(f.path)
 			if err != nil {
+				if vfs.IsErrNotExist(err) {
+					continue
+				}
 				return nil, err
 			}
-			return newFileData(f.fs, f.base, fpath, fi), nil
-		case del:
-			return nil, vfs.ErrNotExist
-		case opaque_del:
-			forward = false
+			if !fi.IsDir() {
+				limit = i
+				break
+			}
 		}
-	}
-	if forward {
-		fi, err := f.base.Lstat(fpath)
-		if err != nil {
-			return nil, err
+		if found == nil {
+			fi, err := fs.Lstat(fpath)
+			if err == nil {
+				found = newFileData(f.layers, i, fpath, fi)
+				if !fi.IsDir() {
+					return found, nil
+				}
+			} else if !vfs.IsErrNotExist(err) {
+				return nil, err
+			}
+		}
+		if ok, _ := vfs.Exists(fs, vfs.Join(fs, f.path, del_prefix+name)); ok {
+			limit = i + 1
+			break
 		}
-		return newFileData(f.base, nil, fpath, fi), nil
+		if ok, _ := vfs.Exists(fs, vfs.Join(fs, f.path, opaque_del)); ok {
+			limit = i + 1
+			break
+		}
+	}
+	if found == nil {
+		return nil, vfs.ErrNotExist
 	}
-	return nil, vfs.ErrNotExist
+	found.limit = limit
+	return found, nil
 }
 
 func (f *fileData) GetSymlink() string {
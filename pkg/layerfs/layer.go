@@ -30,37 +30,106 @@ import (
 	"github.com/mandelsoft/vfs/pkg/vfs"
 )
 
+// LayerFileSystem is a union of a writable layer and
+// a stack of read-only layers.
 type LayerFileSystem struct {
 	utils.FileSystemBase
-	layer vfs.FileSystem
-	base  vfs.FileSystem
+	layer  vfs.FileSystem
+	layers []vfs.FileSystem
 }
 
 var _ vfs.FileSystemCleanup = (*LayerFileSystem)(nil)
+var _ vfs.OwnershipFileSystem = (*LayerFileSystem)(nil)
+var _ vfs.LinkFileSystem = (*LayerFileSystem)(nil)
+var _ vfs.XattrFileSystem = (*LayerFileSystem)(nil)
 
 func New(layer, base vfs.FileSystem) vfs.FileSystem {
-	fs := &LayerFileSystem{layer: layer, base: base}
-	return fs
+	return NewUnion(layer, base)
 }
 
-func (l *LayerFileSystem) Cleanup() error {
-	err := vfs.Cleanup(l.layer)
-	err2 := vfs.Cleanup(l.base)
+// NewUnion provides a union filesystem for a writable layer on top of
+// a stack of read-only layers given from top to bottom. All modifications
+// are done in the writable layer. Entries of the read-only layers are
+// hidden by whiteout files of higher layers.
+func NewUnion(layer vfs.FileSystem, lowers ...vfs.FileSystem) *LayerFileSystem {
+	return &LayerFileSystem{layer: layer, layers: append([]vfs.FileSystem{layer}, lowers...)}
+}
 
-	if err == nil {
-		if err2 != nil {
-			return err2
-		}
-	} else {
-		if err2 != nil {
-			return fmt.Errorf("error cleaning layer: layer %s, base %s", err2.Error(), err.Error())
+func (l *LayerFileSystem) Cleanup() error {
+	var errs []string
+	for i, fs := range l.layers {
+		if err := vfs.Cleanup(fs); err != nil {
+			errs = append(errs, fmt.Sprintf("layer %d: %s", i, err))
 		}
 	}
-	return err
+	if len(errs) > 0 {
+		return fmt.Errorf("error cleaning layers: %s", strings.Join(errs, ", "))
+	}
+	return nil
 }
 
 func (l *LayerFileSystem) Name() string {
-	return fmt.Sprintf("LayerFileSystem %s[%s]", l.layer, l.base)
+	return fmt.Sprintf("LayerFileSystem %s%v", l.layer, l.layers[1:])
+}
+
+// Layers provides the layer stack from top to bottom.
+// The first entry is the writable layer.
+func (l *LayerFileSystem) Layers() []vfs.FileSystem {
+	return append([]vfs.FileSystem{}, l.layers...)
+}
+
+// Origin provides the index and the filesystem of the layer providing
+// the given path. Index 0 is the writable layer. Like Lstat, a final
+// symbolic link is not followed.
+func (l *LayerFileSystem) Origin(name string) (int, vfs.FileSystem, error) {
+	f, _, err := l.findFile(name, false)
+	if err != nil {
+		return -1, nil, &os.PathError{Op: "origin", Path: name, Err: err}
+	}
+	return f.index, f.fs, nil
+}
+
+// lowerUnion provides the union of the read-only layers.
+// It is nil, if there are no read-only layers.
+func (l *LayerFileSystem) lowerUnion() *LayerFileSystem {
+	if len(l.layers) < 2 {
+		return nil
+	}
+	return NewUnion(l.layers[1], l.layers[2:]...)
+}
+
+// lower provides the topmost read-only layer providing the
+// given path, respecting the whiteouts of the read-only layers.
+// The path must not contain symbolic links.
+func (l *LayerFileSystem) lower(path string) (vfs.FileSystem, os.FileInfo, error) {
+	u := l.lowerUnion()
+	if u == nil {
+		return nil, nil, vfs.ErrNotExist
+	}
+	f, _, err := u.findFile(path, false)
+	if err != nil {
+		if vfs.IsErrNotExist(err) || vfs.IsErrNotDir(err) {
+			err = vfs.ErrNotExist
+		}
+		return nil, nil, err
+	}
+	fi, err := f.Lstat()
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.fs, fi, nil
+}
+
+// shadowed reports whether any read-only layer contains an entry
+// for the given path, regardless of whether it is hidden by a
+// whiteout or not.
+func (l *LayerFileSystem) shadowed(path string) bool {
+	for _, fs := range l.layers[1:] {
+		if _, err := fs.Lstat(path); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (l *LayerFileSystem) findFile(name string, link ...bool) (*fileData, string, error) {
@@ -75,7 +144,7 @@ func (l *LayerFileSystem) findFile(name string, link ...bool) (*fileData, string
 }
 
 func (l *LayerFileSystem) createInfo(name string, link ...bool) (*fileData, string, *fileData, string, error) {
-	d, dn, f, fn, err := utils.EvaluatePath(l, newFileData(l.layer, l.base, vfs.PathSeparatorString, nil), name, link...)
+	d, dn, f, fn, err := utils.EvaluatePath(l, newFileData(l.layers, 0, vfs.PathSeparatorString, nil), name, link...)
 	return asFileData(d), dn, asFileData(f), fn, err
 }
 
@@ -92,7 +161,7 @@ func (l *LayerFileSystem) propagateDirectories(path string) error {
 		if !vfs.IsErrNotExist(err) {
 			return err
 		}
-		fi, err = l.base.Lstat(path)
+		fs, fi, err := l.lower(path)
 		if err != nil {
 			return err
 		}
@@ -104,33 +173,59 @@ func (l *LayerFileSystem) propagateDirectories(path string) error {
 		if err != nil {
 			return err
 		}
+		l.preserveMetadata(fs, fi, path)
 	}
 	return nil
 }
 
-func (l *LayerFileSystem) copy(fi os.FileInfo, path string) (*fileData, error) {
-	f := newFileData(l.layer, l.base, path, fi)
+// preserveMetadata propagates the ownership and the extended
+// attributes of an entry of a read-only layer to the writable
+// layer on a best effort basis.
+func (l *LayerFileSystem) preserveMetadata(fs vfs.FileSystem, fi os.FileInfo, path string) {
+	vfs.CopyXattrs(fs, path, l.layer, path)
+	if uid, gid, ok := vfs.Owner(fi); ok {
+		vfs.Lchown(l.layer, path, uid, gid)
+	}
+}
+
+// copy copies an entry of a read-only layer to the writable layer.
+func (l *LayerFileSystem) copy(src *fileData) (*fileData, error) {
+	fi, err := src.Lstat()
+	if err != nil {
+		return nil, err
+	}
+	path := src.path
+	f := newFileData(l.layers, 0, path, fi)
+	f.limit = src.limit
 	if fi.IsDir() {
 		return f, l.propagateDirectories(path)
 	}
 
 	dir := vfs.Dir(l.layer, path)
-	err := l.propagateDirectories(dir)
+	err = l.propagateDirectories(dir)
 	if err != nil {
 		return f, err
 	}
 
 	if fi.Mode()&os.ModeSymlink != 0 {
-		old, err := f.fs.Readlink(f.path)
+		old, err := src.fs.Readlink(f.path)
 		if err != nil {
 			return f, err
 		}
-		return f, l.layer.Symlink(old, f.path)
+		err = l.layer.Symlink(old, f.path)
+		if err == nil {
+			l.preserveMetadata(src.fs, fi, f.path)
+		}
+		return f, err
 	}
 	if !fi.Mode().IsRegular() {
 		return f, errors.New("file type not supported")
 	}
-	return f, vfs.CopyFile(l.base, path, l.layer, path)
+	err = vfs.CopyFile(src.fs, path, l.layer, path)
+	if err == nil {
+		l.preserveMetadata(src.fs, fi, path)
+	}
+	return f, err
 }
 
 func (l *LayerFileSystem) create(name string, fn func(path string, deleted bool) (vfs.File, error)) (vfs.File, error) {
@@ -143,16 +238,17 @@ func (l *LayerFileSystem) create(name string, fn func(path string, deleted bool)
 	}
 
 	path := vfs.Join(l.layer, parent.path, n)
-	// entry was formerly deleted from layer, if
-	// - it is explicitly marked as deleted
-	// - the complete base folder content is marked as deleted AND the base layer contains the entry
+	// entry was formerly deleted, if
+	// - it is explicitly marked as deleted in the layer
+	// - any read-only layer contains a (hidden) entry. It may be hidden
+	//   by a whiteout of the entry or one of its parent directories in
+	//   the layer or in a higher read-only layer.
+	// A new directory is marked as opaque to keep the content of
+	// the hidden entries hidden.
 	del := vfs.Join(l.layer, parent.path, del_prefix+n)
 	deleted, _ := vfs.Exists(l.layer, del)
 	if !deleted {
-		deleted, _ = vfs.Exists(l.layer, vfs.Join(l.layer, parent.path, opaque_del))
-		if deleted {
-			deleted, _ = vfs.Exists(l.base, path)
-		}
+		deleted = l.shadowed(path)
 	}
 
 	err = l.propagateDirectories(parent.path)
@@ -218,9 +314,6 @@ func (l *LayerFileSystem) Open(name string) (vfs.File, error) {
 	if err != nil {
 		return nil, err
 	}
-	if f.base == nil {
-		return file, err
-	}
 	return newFileHandle(n, f, file), nil
 }
 
@@ -239,7 +332,7 @@ func (l *LayerFileSystem) OpenFile(name string, flags int, perm os.FileMode) (vf
 		})
 	}
 
-	if f.base == nil {
+	if !f.inLayer() {
 		fi, err := f.fs.Lstat(f.path)
 		if err != nil {
 			return nil, err
@@ -253,7 +346,7 @@ func (l *LayerFileSystem) OpenFile(name string, flags int, perm os.FileMode) (vf
 					}
 					return l.layer.OpenFile(vfs.Join(l.layer, d.path, n), flags|os.O_CREATE, perm)
 				}
-				f, err = l.copy(fi, f.path)
+				f, err = l.copy(f)
 				if err != nil {
 					return nil, err
 				}
@@ -265,9 +358,6 @@ func (l *LayerFileSystem) OpenFile(name string, flags int, perm os.FileMode) (vf
 	if err != nil {
 		return nil, err
 	}
-	if f.base == nil {
-		return file, err
-	}
 	return newFileHandle(n, f, file), nil
 }
 
@@ -306,7 +396,7 @@ func (l *LayerFileSystem) Remove(name string) error {
 	if n == "" {
 		return errors.New("cannot delete root dir")
 	}
-	if f.base == nil {
+	if !f.inLayer() {
 		if fi.IsDir() {
 			err = l.propagateDirectories(f.path)
 			if err != nil {
@@ -345,13 +435,13 @@ func (l *LayerFileSystem) RemoveAll(name string) error {
 		return errors.New("cannot delete root dir")
 	}
 
-	if f.base != nil {
+	if f.inLayer() {
 		err = l.layer.RemoveAll(f.path)
 		if err != nil {
 			return err
 		}
 
-		_, err := f.base.Lstat(f.path)
+		_, _, err := l.lower(f.path)
 		if err == nil || !vfs.IsErrNotExist(err) {
 			return err
 		}
@@ -366,27 +456,78 @@ func (l *LayerFileSystem) RemoveAll(name string) error {
 	return err
 }
 
+// Rename renames an entry. Like for rename(2) an existing
+// non-directory is replaced. Entries of read-only layers are
+// copied to the writable layer first. Like for an overlay
+// filesystem, directories can only be renamed, if they are
+// completely provided by the writable layer, otherwise an error
+// matching vfs.IsErrCrossDevice is returned.
 func (l *LayerFileSystem) Rename(oldname, newname string) error {
-	odir, _, fo, o, err := l.createInfo(oldname, false)
+	_, _, fo, o, err := l.createInfo(oldname, false)
 	if err != nil {
 		return err
 	}
 	if o == "" {
 		return errors.New("cannot rename root dir")
 	}
-	ndir, _, fn, _, err := l.createInfo(newname)
+	ndir, _, fn, n, err := l.createInfo(newname, false)
 	if err != nil {
 		return err
 	}
 	if fo == nil {
 		return os.ErrNotExist
 	}
+	fi, err := fo.Lstat()
+	if err != nil {
+		return err
+	}
+	npath := vfs.Join(l.layer, ndir.path, n)
 	if fn != nil {
-		return os.ErrExist
+		if fn.path == fo.path {
+			return nil
+		}
+		nfi, err := fn.Lstat()
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || nfi.IsDir() {
+			return os.ErrExist
+		}
 	}
-	// TODO rename
-	_, _ = ndir, odir
-	return fmt.Errorf("rename not implemented yet")
+	if fi.IsDir() {
+		if !fo.inLayer() || l.inLower(fo.path) || l.inLower(npath) {
+			return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: vfs.ErrCrossDevice}
+		}
+	}
+
+	if !fo.inLayer() {
+		fo, err = l.copy(fo)
+		if err != nil {
+			return err
+		}
+	}
+	err = l.propagateDirectories(ndir.path)
+	if err != nil {
+		return err
+	}
+	err = l.layer.Rename(fo.path, npath)
+	if err != nil {
+		return err
+	}
+	err = l.layer.Remove(markerFor(npath))
+	if err != nil && !vfs.IsErrNotExist(err) {
+		return err
+	}
+	if l.inLower(fo.path) {
+		return vfs.Touch(l.layer, markerFor(fo.path), os.ModePerm)
+	}
+	return nil
+}
+
+// inLower reports whether an entry is provided by a read-only layer.
+func (l *LayerFileSystem) inLower(path string) bool {
+	_, _, err := l.lower(path)
+	return err == nil
 }
 
 func (l *LayerFileSystem) Lstat(name string) (os.FileInfo, error) {
@@ -413,7 +554,7 @@ func (l *LayerFileSystem) Chmod(name string, mode os.FileMode) error {
 	if err != nil {
 		return err
 	}
-	if f.base == nil {
+	if !f.inLayer() {
 		fi, err := f.fs.Lstat(f.path)
 		if err != nil {
 			return err
@@ -421,7 +562,7 @@ func (l *LayerFileSystem) Chmod(name string, mode os.FileMode) error {
 		if fi.Mode().Perm() == mode.Perm() {
 			return nil
 		}
-		f, err = l.copy(fi, f.path)
+		f, err = l.copy(f)
 		if err != nil {
 			return err
 		}
@@ -436,7 +577,7 @@ func (l *LayerFileSystem) Chtimes(name string, atime time.Time, mtime time.Time)
 		return err
 	}
 
-	if f.base == nil {
+	if !f.inLayer() {
 		fi, err := f.fs.Lstat(f.path)
 		if err != nil {
 			return err
@@ -444,7 +585,7 @@ func (l *LayerFileSystem) Chtimes(name string, atime time.Time, mtime time.Time)
 		if fi.ModTime() == mtime {
 			return nil
 		}
-		f, err = l.copy(fi, f.path)
+		f, err = l.copy(f)
 		if err != nil {
 			return err
 		}
@@ -454,6 +595,37 @@ func (l *LayerFileSystem) Chtimes(name string, atime time.Time, mtime time.Time)
 	return nil
 }
 
+func (l *LayerFileSystem) Chown(name string, uid, gid int) error {
+	return l.chown(name, uid, gid, true)
+}
+
+func (l *LayerFileSystem) Lchown(name string, uid, gid int) error {
+	return l.chown(name, uid, gid, false)
+}
+
+func (l *LayerFileSystem) chown(name string, uid, gid int, link bool) error {
+	f, _, err := l.findFile(name, link)
+	if err != nil {
+		return err
+	}
+
+	if !f.inLayer() {
+		fi, err := f.fs.Lstat(f.path)
+		if err != nil {
+			return err
+		}
+		ouid, ogid, ok := vfs.Owner(fi)
+		if ok && (uid == -1 || uid == ouid) && (gid == -1 || gid == ogid) {
+			return nil
+		}
+		f, err = l.copy(f)
+		if err != nil {
+			return err
+		}
+	}
+	return vfs.Lchown(f.fs, f.path, uid, gid)
+}
+
 func (l *LayerFileSystem) Symlink(oldname, newname string) error {
 	_, err := l.create(newname, func(path string, deleted bool) (vfs.File, error) {
 		return nil, l.layer.Symlink(oldname, path)
@@ -461,6 +633,32 @@ func (l *LayerFileSystem) Symlink(oldname, newname string) error {
 	return err
 }
 
+// Link creates a hard link in the layer. If the old file is
+// provided by a read-only layer, it is copied to the layer first.
+func (l *LayerFileSystem) Link(oldname, newname string) error {
+	f, _, err := l.findFile(oldname, false)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	fi, err := f.Lstat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrPermission}
+	}
+	if !f.inLayer() {
+		f, err = l.copy(f)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = l.create(newname, func(path string, deleted bool) (vfs.File, error) {
+		return nil, vfs.Link(l.layer, f.path, path)
+	})
+	return err
+}
+
 func (l *LayerFileSystem) Readlink(name string) (string, error) {
 	f, _, err := l.findFile(name, false)
 	if err != nil {
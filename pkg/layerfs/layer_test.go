@@ -19,6 +19,9 @@
 package layerfs
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
 	"os"
 
 	. "github.com/onsi/ginkgo"
@@ -129,6 +132,49 @@ var _ = Describe("layer filesystem", func() {
 				ExpectFolders(layer, "/base/d1", []string{".wh..wh..opq"}, nil)
 			})
 		})
+		Context("rename", func() {
+			It("renames file from base", func() {
+				Expect(fs.Rename("base/d1/basefile", "base/newfile")).To(Succeed())
+				ExpectFolders(fs, "base", []string{"basefile", "d1", "newfile"}, nil)
+				ExpectFolders(fs, "base/d1", []string{"otherfile"}, nil)
+				ExpectFolders(layer, "base/d1", []string{".wh.basefile"}, nil)
+				ExpectFileContent(fs, "base/newfile", DefaultContent)
+				ExpectFolders(base, "base/d1", []string{"basefile", "otherfile"}, nil)
+			})
+			It("replaces file from base", func() {
+				content := []byte("other content")
+				ExpectFileCreate(fs, "base/tmp", content, nil)
+				Expect(fs.Rename("base/tmp", "base/basefile")).To(Succeed())
+				ExpectFolders(fs, "base", []string{"basefile", "d1"}, nil)
+				ExpectFolders(layer, "base", []string{"basefile"}, nil)
+				ExpectFileContent(fs, "base/basefile", content)
+			})
+			It("recreates deleted entry", func() {
+				Expect(fs.Remove("base/basefile")).To(Succeed())
+				ExpectFileCreate(fs, "base/tmp", DefaultContent, nil)
+				Expect(fs.Rename("base/tmp", "base/basefile")).To(Succeed())
+				ExpectFolders(fs, "base", []string{"basefile", "d1"}, nil)
+				ExpectFolders(layer, "base", []string{"basefile"}, nil)
+			})
+			It("renames directory of layer", func() {
+				Expect(fs.MkdirAll("d1/d2", os.ModePerm)).To(Succeed())
+				Expect(fs.Rename("d1", "d3")).To(Succeed())
+				ExpectFolders(fs, "/", []string{"base", "d3"}, nil)
+				ExpectFolders(fs, "d3", []string{"d2"}, nil)
+			})
+			It("rejects directory from base", func() {
+				err := fs.Rename("base/d1", "base/d2")
+				Expect(vfs.IsErrCrossDevice(err)).To(BeTrue())
+			})
+			It("writes files atomically", func() {
+				content := []byte("other content")
+				Expect(vfs.WriteFileAtomic(fs, "base/d1/basefile", content, os.ModePerm)).To(Succeed())
+				ExpectFileContent(fs, "base/d1/basefile", content)
+				ExpectFileContent(base, "base/d1/basefile", DefaultContent)
+				ExpectFolders(fs, "base/d1", []string{"basefile", "otherfile"}, nil)
+				ExpectFolders(layer, "base/d1", []string{"basefile"}, nil)
+			})
+		})
 		Context("file", func() {
 			It("show file from base", func() {
 				ExpectFileContent(fs, "base/d1/basefile", DefaultContent)
@@ -154,6 +200,88 @@ var _ = Describe("layer filesystem", func() {
 				ExpectFolders(layer, "base", []string{"d1"}, nil)
 				ExpectFolders(layer, "base/d1", []string{"basefile"}, nil)
 			})
+			It("chown file from base", func() {
+				Expect(vfs.Chown(fs, "base/d1/basefile", 4711, 42)).To(Succeed())
+				ExpectFileContent(fs, "base/d1/basefile", DefaultContent)
+				ExpectFolders(layer, "base/d1", []string{"basefile"}, nil)
+
+				fi, err := fs.Stat("base/d1/basefile")
+				Expect(err).To(Succeed())
+				uid, gid, _ := vfs.Owner(fi)
+				Expect([]int{uid, gid}).To(Equal([]int{4711, 42}))
+
+				fi, err = base.Stat("base/d1/basefile")
+				Expect(err).To(Succeed())
+				uid, gid, _ = vfs.Owner(fi)
+				Expect([]int{uid, gid}).To(Equal([]int{os.Getuid(), os.Getgid()}))
+			})
+			It("set xattr of file from base", func() {
+				Expect(vfs.Setxattr(base, "base/d1/basefile", "user.a", []byte("base"), 0)).To(Succeed())
+				Expect(vfs.Getxattr(fs, "base/d1/basefile", "user.a")).To(Equal([]byte("base")))
+				ExpectFolders(layer, "/", nil, nil)
+
+				Expect(vfs.Setxattr(fs, "base/d1/basefile", "user.b", []byte("layer"), 0)).To(Succeed())
+				ExpectFolders(layer, "base/d1", []string{"basefile"}, nil)
+				Expect(vfs.Listxattr(fs, "base/d1/basefile")).To(Equal([]string{"user.a", "user.b"}))
+				Expect(vfs.Listxattr(base, "base/d1/basefile")).To(Equal([]string{"user.a"}))
+			})
+			It("remove xattr of file from base", func() {
+				Expect(vfs.Setxattr(base, "base/d1/basefile", "user.a", []byte("base"), 0)).To(Succeed())
+				Expect(vfs.IsErrNoAttr(vfs.Removexattr(fs, "base/d1/basefile", "user.b"))).To(BeTrue())
+				ExpectFolders(layer, "/", nil, nil)
+				Expect(vfs.Removexattr(fs, "base/d1/basefile", "user.a")).To(Succeed())
+				Expect(vfs.Listxattr(fs, "base/d1/basefile")).To(Equal([]string{}))
+				Expect(vfs.Listxattr(base, "base/d1/basefile")).To(Equal([]string{"user.a"}))
+			})
+		})
+
+		Context("OCI layer", func() {
+			BeforeEach(func() {
+				Expect(fs.Remove("base/basefile")).To(Succeed())
+				Expect(fs.RemoveAll("base/d1")).To(Succeed())
+				Expect(fs.Mkdir("base/d1", os.ModePerm)).To(Succeed())
+				Expect(vfs.WriteFile(fs, "base/d1/newfile", DefaultContent, os.ModePerm)).To(Succeed())
+				Expect(vfs.WriteFile(fs, "new", DefaultContent, os.ModePerm)).To(Succeed())
+			})
+
+			It("exports whiteouts", func() {
+				var buf bytes.Buffer
+				Expect(Export(fs, &buf)).To(Succeed())
+				tr := tar.NewReader(&buf)
+				var names []string
+				for {
+					h, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					Expect(err).To(Succeed())
+					names = append(names, h.Name)
+				}
+				Expect(names).To(Equal([]string{
+					"base/",
+					"base/.wh.basefile",
+					"base/d1/",
+					"base/d1/.wh..wh..opq",
+					"base/d1/newfile",
+					"new",
+				}))
+			})
+
+			It("imports layer", func() {
+				var buf bytes.Buffer
+				Expect(Export(fs, &buf)).To(Succeed())
+				_, _, base := NewTestEnv()
+				result, err := Import(base, &buf)
+				Expect(err).To(Succeed())
+				ExpectFolders(result, "/", []string{"base", "new"}, nil)
+				ExpectFolders(result, "base", []string{"d1"}, nil)
+				ExpectFolders(result, "base/d1", []string{"newfile"}, nil)
+				ExpectFileContent(result, "base/d1/newfile", string(DefaultContent))
+			})
+
+			It("rejects other filesystems", func() {
+				Expect(Export(base, io.Discard)).NotTo(Succeed())
+			})
 		})
 	})
 })
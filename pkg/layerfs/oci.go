@@ -0,0 +1,66 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/tarfs"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// Layer provides access to the filesystem used as layer.
+func (l *LayerFileSystem) Layer() vfs.FileSystem {
+	return l.layer
+}
+
+// Base provides access to the filesystem used as base,
+// which is the topmost read-only layer of the layer stack.
+func (l *LayerFileSystem) Base() vfs.FileSystem {
+	if len(l.layers) < 2 {
+		return nil
+	}
+	return l.layers[1]
+}
+
+// Export writes the layer of a layered filesystem as OCI (or Docker)
+// layer tar archive to w. Deleted entries of the base filesystem are
+// described by whiteout entries (.wh.<name>) and replaced directories by
+// opaque whiteouts (.wh..wh..opq).
+func Export(fs vfs.FileSystem, w io.Writer) error {
+	l, ok := fs.(*LayerFileSystem)
+	if !ok {
+		return fmt.Errorf("%s is no layered filesystem", fs.Name())
+	}
+	return tarfs.Export(l.layer, w)
+}
+
+// Import applies the OCI (or Docker) layer tar archive read from r
+// as a new memory based layer on top of the given base filesystem.
+// The archive may be gzip compressed.
+func Import(base vfs.FileSystem, r io.Reader) (vfs.FileSystem, error) {
+	layer := memoryfs.New()
+	err := tarfs.Extract(layer, r)
+	if err != nil {
+		return nil, err
+	}
+	return New(layer, base), nil
+}
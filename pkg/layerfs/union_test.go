@@ -0,0 +1,164 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("union filesystem", func() {
+	var fs *LayerFileSystem
+	var layer, top, middle, bottom vfs.FileSystem
+
+	write := func(fs vfs.FileSystem, path, content string) {
+		Expect(fs.MkdirAll(vfs.Dir(fs, path), os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(fs, path, []byte(content), os.ModePerm)).To(Succeed())
+	}
+
+	origin := func(path string) int {
+		i, _, err := fs.Origin(path)
+		Expect(err).To(Succeed())
+		return i
+	}
+
+	BeforeEach(func() {
+		bottom = memoryfs.New()
+		write(bottom, "/a/f1", "bottom f1")
+		write(bottom, "/a/f2", "bottom f2")
+		write(bottom, "/b/f3", "bottom f3")
+		write(bottom, "/c/f4", "bottom f4")
+
+		middle = memoryfs.New()
+		write(middle, "/a/.wh.f1", "")
+		write(middle, "/b/.wh..wh..opq", "")
+		write(middle, "/b/f5", "middle f5")
+		write(middle, "/c", "middle c")
+
+		top = memoryfs.New()
+		write(top, "/a/f6", "top f6")
+
+		layer = memoryfs.New()
+		fs = NewUnion(layer, top, middle, bottom)
+	})
+
+	It("merges layers", func() {
+		ExpectFolders(fs, "/", []string{"a", "b", "c"}, nil)
+		ExpectFolders(fs, "/a", []string{"f2", "f6"}, nil)
+		ExpectFolders(fs, "/b", []string{"f5"}, nil)
+		ExpectFileContent(fs, "/a/f2", "bottom f2")
+		ExpectFileContent(fs, "/a/f6", "top f6")
+		ExpectFileContent(fs, "/c", "middle c")
+
+		_, err := fs.Stat("/a/f1")
+		Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+		_, err = fs.Stat("/b/f3")
+		Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+		_, err = fs.Stat("/c/f4")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports origin", func() {
+		Expect(origin("/a")).To(Equal(1))
+		Expect(origin("/a/f6")).To(Equal(1))
+		Expect(origin("/a/f2")).To(Equal(3))
+		Expect(origin("/b/f5")).To(Equal(2))
+		Expect(origin("/c")).To(Equal(2))
+		_, ofs, err := fs.Origin("/a/f2")
+		Expect(err).To(Succeed())
+		Expect(ofs).To(BeIdenticalTo(bottom))
+		Expect(fs.Layers()).To(Equal([]vfs.FileSystem{layer, top, middle, bottom}))
+
+		_, _, err = fs.Origin("/a/f1")
+		Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+	})
+
+	It("copies up from lower layers", func() {
+		Expect(vfs.WriteFile(fs, "/a/f2", []byte("modified"), os.ModePerm)).To(Succeed())
+		Expect(origin("/a/f2")).To(Equal(0))
+		ExpectFileContent(fs, "/a/f2", "modified")
+		ExpectFileContent(bottom, "/a/f2", "bottom f2")
+
+		Expect(fs.Chmod("/b/f5", 0o600)).To(Succeed())
+		Expect(origin("/b/f5")).To(Equal(0))
+		ExpectFileContent(layer, "/b/f5", "middle f5")
+		ExpectFolders(fs, "/b", []string{"f5"}, nil)
+	})
+
+	It("removes entries of lower layers", func() {
+		Expect(fs.Remove("/a/f2")).To(Succeed())
+		Expect(fs.RemoveAll("/b")).To(Succeed())
+		ExpectFolders(fs, "/a", []string{"f6"}, nil)
+		ExpectFolders(fs, "/", []string{"a", "c"}, nil)
+
+		Expect(fs.Mkdir("/b", os.ModePerm)).To(Succeed())
+		ExpectFolders(fs, "/b", nil, nil)
+		Expect(vfs.WriteFile(fs, "/a/f1", []byte("new"), os.ModePerm)).To(Succeed())
+		ExpectFolders(fs, "/a", []string{"f1", "f6"}, nil)
+		ExpectFileContent(fs, "/a/f1", "new")
+	})
+	Context("whiteouts of middle layers", func() {
+		var l1, l2 vfs.FileSystem
+
+		BeforeEach(func() {
+			l2 = memoryfs.New()
+			write(l2, "/a/x", "l2 x")
+			write(l2, "/a/d/y", "l2 y")
+			l1 = memoryfs.New()
+			write(l1, "/.wh.a", "")
+			layer = memoryfs.New()
+			fs = NewUnion(layer, l1, l2)
+		})
+
+		It("hides the content of re-created directories", func() {
+			_, err := fs.Stat("/a")
+			Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+
+			Expect(fs.Mkdir("/a", os.ModePerm)).To(Succeed())
+			ExpectFolders(fs, "/a", nil, nil)
+			_, err = fs.Stat("/a/x")
+			Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+			Expect(vfs.Exists(layer, "/a/"+opaque_del)).To(BeTrue())
+
+			Expect(fs.MkdirAll("/a/d", os.ModePerm)).To(Succeed())
+			ExpectFolders(fs, "/a/d", nil, nil)
+		})
+
+		It("hides the content without opaque marker", func() {
+			write(layer, "/a/z", "layer z")
+			ExpectFolders(fs, "/a", []string{"z"}, nil)
+			_, err := fs.Stat("/a/x")
+			Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+			_, err = fs.Stat("/a/d/y")
+			Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+		})
+
+		It("does not propagate hidden directories", func() {
+			Expect(fs.MkdirAll("/a/d", os.ModePerm)).To(Succeed())
+			ExpectFolders(fs, "/a", []string{"d"}, nil)
+			ExpectFolders(fs, "/a/d", nil, nil)
+		})
+	})
+})
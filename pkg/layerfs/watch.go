@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mandelsoft/vfs/pkg/utils"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ vfs.WatchableFileSystem = (*LayerFileSystem)(nil)
+
+// Watch reports the changes of a path. All changes are
+// recorded in the writable layer, therefore, this requires
+// a watchable layer filesystem. The creation of a whiteout
+// is reported as removal of the hidden entry, and the copy-up of
+// entries of lower layers is not reported as creation.
+// In the writable layer the parent directory of the path (or its
+// nearest existing ancestor) is watched, because whiteouts are
+// kept in the parent directory of a removed entry.
+func (l *LayerFileSystem) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	path, err := vfs.Canonical(l, name, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	fi, err := l.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	parent := vfs.Dir(l.layer, path)
+	root := parent
+	for !isDir(l.layer, root) && root != vfs.PathSeparatorString {
+		root = vfs.Dir(l.layer, root)
+	}
+	// events for the content of a directory are only reported
+	// by a recursive watch of its parent.
+	rec := recursive || root != parent || fi.IsDir()
+	w, err := vfs.Watch(l.layer, root, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	// whiteouts, whose removal has not yet been reported.
+	// A new entry is created before the whiteout is removed,
+	// so they indicate the re-creation of a deleted entry.
+	whiteouts, err := l.whiteouts(root, rec)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	mapping := func(e vfs.Event) (vfs.Event, bool) {
+		dir, base := vfs.Split(l.layer, e.Path)
+		if strings.HasPrefix(base, del_prefix) {
+			if base == opaque_del {
+				return e, false
+			}
+			hidden := vfs.Join(l.layer, dir, base[len(del_prefix):])
+			if !e.Op.Has(vfs.EventCreate) {
+				if e.Op.Has(vfs.EventRemove) {
+					delete(whiteouts, hidden)
+				}
+				return e, false
+			}
+			whiteouts[hidden] = true
+			e = vfs.Event{Path: hidden, Op: vfs.EventRemove}
+		}
+		if !utils.Watched(path, recursive, e.Path) {
+			return e, false
+		}
+		if e.Op.Has(vfs.EventCreate) && !whiteouts[e.Path] {
+			// copy-up of entries of lower layers
+			if _, fi, err := l.lower(e.Path); err == nil && fi != nil {
+				e.Op &^= vfs.EventCreate
+				if !fi.IsDir() {
+					e.Op |= vfs.EventWrite
+				}
+			}
+		}
+		return e, e.Op != 0
+	}
+	return utils.NewMappedWatcher(mapping, w), nil
+}
+
+// whiteouts provides the paths of the entries marked as deleted
+// in a directory of the writable layer.
+func (l *LayerFileSystem) whiteouts(root string, recursive bool) (map[string]bool, error) {
+	whiteouts := map[string]bool{}
+	err := vfs.Walk(l.layer, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && !recursive {
+				return vfs.SkipDir
+			}
+			return nil
+		}
+		dir, base := vfs.Split(l.layer, path)
+		if strings.HasPrefix(base, del_prefix) && base != opaque_del {
+			whiteouts[vfs.Join(l.layer, dir, base[len(del_prefix):])] = true
+		}
+		return nil
+	})
+	return whiteouts, err
+}
+
+func isDir(fs vfs.FileSystem, path string) bool {
+	ok, _ := vfs.IsDir(fs, path)
+	return ok
+}
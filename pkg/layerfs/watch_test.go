@@ -0,0 +1,103 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("watch", func() {
+	var fs *LayerFileSystem
+
+	BeforeEach(func() {
+		base := memoryfs.New()
+		Expect(base.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		Expect(vfs.WriteFile(base, "/d1/f1", nil, os.ModePerm)).To(Succeed())
+		fs = New(memoryfs.New(), base).(*LayerFileSystem)
+	})
+
+	It("reports changes of paths only present in lower layers", func() {
+		Expect(vfs.WriteFile(fs, "/d1/d2/f0", nil, os.ModePerm)).To(Succeed())
+		w, err := vfs.Watch(fs, "/d1", false)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/f2", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/f2", vfs.EventCreate)
+		Expect(fs.Remove("/d1/f1")).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventRemove)
+		Expect(vfs.WriteFile(fs, "/d1/d2/f3", nil, os.ModePerm)).To(Succeed())
+		ExpectNoEvent(w)
+	})
+
+	It("reports changes recursively", func() {
+		w, err := vfs.Watch(fs, "/d1", true)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/d2/f3", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/d2/f3", vfs.EventCreate)
+		Expect(fs.RemoveAll("/d1/d2")).To(Succeed())
+		ExpectEvent(w, "/d1/d2", vfs.EventRemove)
+	})
+
+	It("reports re-created entries of lower layers", func() {
+		w, err := vfs.Watch(fs, "/d1", false)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(fs.Remove("/d1/f1")).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventRemove)
+		Expect(vfs.WriteFile(fs, "/d1/f1", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventCreate)
+	})
+
+	It("reports re-created entries deleted before the watch", func() {
+		Expect(fs.Remove("/d1/f1")).To(Succeed())
+		w, err := vfs.Watch(fs, "/d1", false)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/f1", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventCreate)
+	})
+
+	It("reports changes of files", func() {
+		w, err := vfs.Watch(fs, "/d1/f1", false)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/f1", []byte("new"), os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventWrite)
+		Expect(fs.Remove("/d1/f1")).To(Succeed())
+		ExpectEvent(w, "/d1/f1", vfs.EventRemove)
+	})
+
+	It("rejects non-existing paths", func() {
+		_, err := vfs.Watch(fs, "/d2", false)
+		Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+	})
+})
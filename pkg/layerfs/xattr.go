@@ -0,0 +1,120 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package layerfs
+
+import (
+	"os"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+func (l *LayerFileSystem) xattrFile(op, name string, link bool) (*fileData, error) {
+	f, _, err := l.findFile(name, link)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return f, nil
+}
+
+// copyUp assures that the given file is provided by the layer.
+func (l *LayerFileSystem) copyUp(f *fileData) (*fileData, error) {
+	if f.inLayer() {
+		return f, nil
+	}
+	return l.copy(f)
+}
+
+func (l *LayerFileSystem) getxattr(op, name, attr string, link bool) ([]byte, error) {
+	f, err := l.xattrFile(op, name, link)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Lgetxattr(f.fs, f.path, attr)
+}
+
+func (l *LayerFileSystem) setxattr(op, name, attr string, data []byte, flags int, link bool) error {
+	f, err := l.xattrFile(op, name, link)
+	if err != nil {
+		return err
+	}
+	f, err = l.copyUp(f)
+	if err != nil {
+		return err
+	}
+	return vfs.Lsetxattr(f.fs, f.path, attr, data, flags)
+}
+
+func (l *LayerFileSystem) listxattr(op, name string, link bool) ([]string, error) {
+	f, err := l.xattrFile(op, name, link)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.Llistxattr(f.fs, f.path)
+}
+
+func (l *LayerFileSystem) removexattr(op, name, attr string, link bool) error {
+	f, err := l.xattrFile(op, name, link)
+	if err != nil {
+		return err
+	}
+	if !f.inLayer() {
+		// avoid an unnecessary copy for a non-existing attribute
+		_, err = vfs.Lgetxattr(f.fs, f.path, attr)
+		if err != nil {
+			return err
+		}
+	}
+	f, err = l.copyUp(f)
+	if err != nil {
+		return err
+	}
+	return vfs.Lremovexattr(f.fs, f.path, attr)
+}
+
+func (l *LayerFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	return l.getxattr("getxattr", name, attr, true)
+}
+
+func (l *LayerFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	return l.setxattr("setxattr", name, attr, data, flags, true)
+}
+
+func (l *LayerFileSystem) Listxattr(name string) ([]string, error) {
+	return l.listxattr("listxattr", name, true)
+}
+
+func (l *LayerFileSystem) Removexattr(name, attr string) error {
+	return l.removexattr("removexattr", name, attr, true)
+}
+
+func (l *LayerFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	return l.getxattr("lgetxattr", name, attr, false)
+}
+
+func (l *LayerFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return l.setxattr("lsetxattr", name, attr, data, flags, false)
+}
+
+func (l *LayerFileSystem) Llistxattr(name string) ([]string, error) {
+	return l.listxattr("llistxattr", name, false)
+}
+
+func (l *LayerFileSystem) Lremovexattr(name, attr string) error {
+	return l.removexattr("lremovexattr", name, attr, false)
+}
@@ -21,6 +21,7 @@ package memoryfs
 import (
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mandelsoft/vfs/pkg/utils"
@@ -33,9 +34,16 @@ type fileData struct {
 	entries DirectoryEntries
 	mode    os.FileMode
 	modtime time.Time
+	uid     int
+	gid     int
+	nlink   atomic.Int32
+	xattrs  map[string][]byte
 }
 
 var _ utils.FileData = &fileData{}
+var _ utils.FileDataOwnership = &fileData{}
+var _ utils.FileDataLinks = &fileData{}
+var _ utils.FileDataXattr = &fileData{}
 
 func (f *fileData) Data() []byte {
 	return f.data
@@ -84,6 +92,61 @@ func (f *fileData) SetModTime(mtime time.Time) {
 	f.modtime = mtime
 }
 
+func (f *fileData) Owner() (int, int) {
+	return f.uid, f.gid
+}
+
+func (f *fileData) SetOwner(uid, gid int) {
+	f.uid = uid
+	f.gid = gid
+}
+
+func (f *fileData) GetXattr(attr string) ([]byte, bool) {
+	data, ok := f.xattrs[attr]
+	return data, ok
+}
+
+func (f *fileData) SetXattr(attr string, data []byte) {
+	if f.xattrs == nil {
+		f.xattrs = map[string][]byte{}
+	}
+	f.xattrs[attr] = data
+}
+
+func (f *fileData) ListXattr() []string {
+	list := []string{}
+	for n := range f.xattrs {
+		list = append(list, n)
+	}
+	return list
+}
+
+func (f *fileData) RemoveXattr(attr string) bool {
+	if _, ok := f.xattrs[attr]; !ok {
+		return false
+	}
+	delete(f.xattrs, attr)
+	return true
+}
+
+func (f *fileData) Links() int {
+	return int(f.nlink.Load())
+}
+
+// unlink removes a directory entry reference.
+// If a directory is not referenced anymore, the
+// references of its entries are released, also.
+// The link count is maintained without locking,
+// because the entry might already be locked by
+// the caller.
+func (f *fileData) unlink() {
+	if f.nlink.Add(-1) == 0 && f.IsDir() {
+		for _, e := range f.entries {
+			e.unlink()
+		}
+	}
+}
+
 func (f *fileData) GetEntry(name string) (utils.FileDataDirAccess, error) {
 	if !f.IsDir() {
 		return nil, vfs.ErrNotDir
@@ -102,7 +165,9 @@ func (f *fileData) Add(name string, s utils.FileData) error {
 	if _, ok := f.entries[name]; ok {
 		return os.ErrExist
 	}
-	f.entries.Add(name, s.(*fileData))
+	e := s.(*fileData)
+	e.nlink.Add(1)
+	f.entries.Add(name, e)
 	f.SetModTime(time.Now())
 	return nil
 }
@@ -111,10 +176,11 @@ func (f *fileData) Del(name string) error {
 	if !f.IsDir() {
 		return vfs.ErrNotDir
 	}
-	_, ok := f.entries[name]
+	e, ok := f.entries[name]
 	if !ok {
 		return vfs.ErrNotExist
 	}
 	delete(f.entries, name)
+	e.unlink()
 	return nil
 }
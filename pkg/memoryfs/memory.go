@@ -30,17 +30,29 @@ type memoryFileSystemAdaper struct{}
 
 func New() vfs.FileSystem {
 	adapter := &memoryFileSystemAdaper{}
-	return utils.NewFSSupport("MemoryFileSystem", adapter.CreateDir(os.ModePerm), adapter)
+	root := adapter.CreateDir(os.ModePerm)
+	root.(*fileData).nlink.Store(1)
+	return utils.NewFSSupport("MemoryFileSystem", root, adapter)
+}
+
+// newFileData creates a new file node owned
+// by the user and group of the actual process.
+func newFileData(mode os.FileMode) *fileData {
+	return &fileData{mode: mode, modtime: time.Now(), uid: os.Getuid(), gid: os.Getgid()}
 }
 
 func (a memoryFileSystemAdaper) CreateFile(perm os.FileMode) utils.FileData {
-	return &fileData{mode: os.ModeTemporary | (perm & os.ModePerm), modtime: time.Now()}
+	return newFileData(os.ModeTemporary | (perm & os.ModePerm))
 }
 
 func (a memoryFileSystemAdaper) CreateDir(perm os.FileMode) utils.FileData {
-	return &fileData{mode: os.ModeDir | os.ModeTemporary | (perm & os.ModePerm), entries: DirectoryEntries{}, modtime: time.Now()}
+	f := newFileData(os.ModeDir | os.ModeTemporary | (perm & os.ModePerm))
+	f.entries = DirectoryEntries{}
+	return f
 }
 
 func (a memoryFileSystemAdaper) CreateSymlink(link string, perm os.FileMode) utils.FileData {
-	return &fileData{mode: os.ModeSymlink | os.ModeTemporary | (perm & os.ModePerm), data: []byte(link), modtime: time.Now()}
+	f := newFileData(os.ModeSymlink | os.ModeTemporary | (perm & os.ModePerm))
+	f.data = []byte(link)
+	return f
 }
@@ -58,6 +58,13 @@ var _ = Describe("memory filesystem", func() {
 		It("fail rename to existent", func() {
 			Expect(fs.Rename("/d1/d1n1", "d1/d1n2")).To(Equal(os.ErrExist))
 		})
+		It("replaces existing file", func() {
+			ExpectFileCreate(fs, "/d1/f1", []byte("old"), nil)
+			ExpectFileCreate(fs, "/d1/f2", []byte("new"), nil)
+			Expect(fs.Rename("/d1/f2", "/d1/f1")).To(Succeed())
+			ExpectFolders(fs, "/d1", []string{"d1n1", "d1n2", "f1"}, nil)
+			ExpectFileContent(fs, "/d1/f1", "new")
+		})
 
 		It("recreate", func() {
 			f, err := fs.Create("create")
@@ -96,4 +103,237 @@ var _ = Describe("memory filesystem", func() {
 			ExpectFolders(fs, "d2/new", []string{"d1n1a"}, nil)
 		})
 	})
+	Context("ownership", func() {
+		BeforeEach(func() {
+			fs.MkdirAll("d1", os.ModePerm)
+			ExpectFileCreate(fs, "d1/f1", nil, nil)
+			Expect(fs.Symlink("f1", "d1/link")).To(Succeed())
+		})
+
+		It("defaults to process owner", func() {
+			fi, err := fs.Stat("d1/f1")
+			Expect(err).To(Succeed())
+			uid, gid, ok := vfs.Owner(fi)
+			Expect(ok).To(BeTrue())
+			Expect(uid).To(Equal(os.Getuid()))
+			Expect(gid).To(Equal(os.Getgid()))
+		})
+
+		It("chown", func() {
+			Expect(vfs.Chown(fs, "d1/link", 4711, 42)).To(Succeed())
+			fi, err := fs.Stat("d1/f1")
+			Expect(err).To(Succeed())
+			uid, gid, _ := vfs.Owner(fi)
+			Expect([]int{uid, gid}).To(Equal([]int{4711, 42}))
+
+			fi, err = fs.Lstat("d1/link")
+			Expect(err).To(Succeed())
+			uid, gid, _ = vfs.Owner(fi)
+			Expect([]int{uid, gid}).To(Equal([]int{os.Getuid(), os.Getgid()}))
+		})
+
+		It("lchown", func() {
+			Expect(vfs.Lchown(fs, "d1/link", 4711, -1)).To(Succeed())
+			fi, err := fs.Lstat("d1/link")
+			Expect(err).To(Succeed())
+			uid, gid, _ := vfs.Owner(fi)
+			Expect([]int{uid, gid}).To(Equal([]int{4711, os.Getgid()}))
+
+			fi, err = fs.Stat("d1/f1")
+			Expect(err).To(Succeed())
+			uid, _, _ = vfs.Owner(fi)
+			Expect(uid).To(Equal(os.Getuid()))
+		})
+	})
+	Context("hard links", func() {
+		nlink := func(path string) uint64 {
+			fi, err := fs.Lstat(path)
+			Expect(err).To(Succeed())
+			n, ok := vfs.Nlink(fi)
+			Expect(ok).To(BeTrue())
+			return n
+		}
+
+		BeforeEach(func() {
+			fs.MkdirAll("d1", os.ModePerm)
+			fs.MkdirAll("d2", os.ModePerm)
+			ExpectFileCreate(fs, "d1/f1", []byte("content"), nil)
+		})
+
+		It("links file", func() {
+			Expect(vfs.Link(fs, "d1/f1", "d2/f2")).To(Succeed())
+			Expect(nlink("d1/f1")).To(Equal(uint64(2)))
+			Expect(nlink("d2/f2")).To(Equal(uint64(2)))
+
+			fi1, _ := fs.Stat("d1/f1")
+			fi2, _ := fs.Stat("d2/f2")
+			Expect(vfs.SameFile(fi1, fi2)).To(BeTrue())
+
+			ExpectFileWrite(fs, "d2/f2", os.O_TRUNC, "modified")
+			ExpectFileContent(fs, "d1/f1", "modified")
+		})
+
+		It("removes link", func() {
+			Expect(vfs.Link(fs, "d1/f1", "d2/f2")).To(Succeed())
+			Expect(fs.Remove("d1/f1")).To(Succeed())
+			Expect(nlink("d2/f2")).To(Equal(uint64(1)))
+			ExpectFileContent(fs, "d2/f2", "content")
+		})
+
+		It("releases links of removed directories", func() {
+			Expect(vfs.Link(fs, "d1/f1", "d2/f2")).To(Succeed())
+			Expect(fs.RemoveAll("d1")).To(Succeed())
+			Expect(nlink("d2/f2")).To(Equal(uint64(1)))
+		})
+
+		It("keeps links on rename", func() {
+			Expect(vfs.Link(fs, "d1/f1", "d2/f2")).To(Succeed())
+			Expect(fs.Rename("d1/f1", "d1/f3")).To(Succeed())
+			Expect(nlink("d2/f2")).To(Equal(uint64(2)))
+		})
+
+		It("rejects directories", func() {
+			err := vfs.Link(fs, "d1", "d3")
+			Expect(vfs.IsErrPermission(err)).To(BeTrue())
+		})
+
+		It("rejects existing targets", func() {
+			err := vfs.Link(fs, "d1/f1", "d2")
+			Expect(vfs.IsErrExist(err)).To(BeTrue())
+		})
+	})
+	Context("xattr", func() {
+		BeforeEach(func() {
+			ExpectFileCreate(fs, "f1", nil, nil)
+			Expect(fs.Symlink("f1", "link")).To(Succeed())
+		})
+
+		It("sets and gets attributes", func() {
+			Expect(vfs.Setxattr(fs, "f1", "user.digest", []byte("sha256:abc"), 0)).To(Succeed())
+			Expect(vfs.Getxattr(fs, "link", "user.digest")).To(Equal([]byte("sha256:abc")))
+			Expect(vfs.Listxattr(fs, "f1")).To(Equal([]string{"user.digest"}))
+			Expect(vfs.Llistxattr(fs, "link")).To(Equal([]string{}))
+		})
+
+		It("handles flags", func() {
+			Expect(vfs.Setxattr(fs, "f1", "user.a", []byte("a"), vfs.XATTR_REPLACE)).To(MatchError(vfs.ErrNoAttr))
+			Expect(vfs.Setxattr(fs, "f1", "user.a", []byte("a"), vfs.XATTR_CREATE)).To(Succeed())
+			Expect(vfs.IsErrExist(vfs.Setxattr(fs, "f1", "user.a", []byte("b"), vfs.XATTR_CREATE))).To(BeTrue())
+			Expect(vfs.Setxattr(fs, "f1", "user.a", []byte("b"), vfs.XATTR_REPLACE)).To(Succeed())
+			Expect(vfs.Getxattr(fs, "f1", "user.a")).To(Equal([]byte("b")))
+		})
+
+		It("removes attributes", func() {
+			Expect(vfs.Lsetxattr(fs, "link", "user.a", []byte("a"), 0)).To(Succeed())
+			Expect(vfs.Listxattr(fs, "link")).To(Equal([]string{}))
+			Expect(vfs.Lremovexattr(fs, "link", "user.a")).To(Succeed())
+			_, err := vfs.Lgetxattr(fs, "link", "user.a")
+			Expect(vfs.IsErrNoAttr(err)).To(BeTrue())
+			Expect(vfs.IsErrNoAttr(vfs.Lremovexattr(fs, "link", "user.a"))).To(BeTrue())
+		})
+	})
+
+	Context("watch", func() {
+		BeforeEach(func() {
+			Expect(fs.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+			Expect(vfs.WriteFile(fs, "/d1/f1", nil, os.ModePerm)).To(Succeed())
+		})
+
+		It("reports changes of directory entries", func() {
+			w, err := vfs.Watch(fs, "/d1", false)
+			Expect(err).To(Succeed())
+			defer w.Close()
+
+			Expect(vfs.WriteFile(fs, "/d1/f2", []byte("data"), os.ModePerm)).To(Succeed())
+			ExpectEvent(w, "/d1/f2", vfs.EventCreate)
+			ExpectEvent(w, "/d1/f2", vfs.EventWrite)
+			Expect(fs.Chmod("/d1/f1", 0o600)).To(Succeed())
+			ExpectEvent(w, "/d1/f1", vfs.EventChmod)
+			Expect(fs.Rename("/d1/f1", "/d1/f3")).To(Succeed())
+			ExpectEvent(w, "/d1/f1", vfs.EventRename)
+			ExpectEvent(w, "/d1/f3", vfs.EventCreate)
+			Expect(fs.Remove("/d1/f3")).To(Succeed())
+			ExpectEvent(w, "/d1/f3", vfs.EventRemove)
+
+			Expect(vfs.WriteFile(fs, "/d1/d2/f", nil, os.ModePerm)).To(Succeed())
+			ExpectNoEvent(w)
+		})
+
+		It("reports changes recursively", func() {
+			w, err := vfs.Watch(fs, "/", true)
+			Expect(err).To(Succeed())
+			defer w.Close()
+
+			Expect(fs.MkdirAll("/d1/d2/d3", os.ModePerm)).To(Succeed())
+			ExpectEvent(w, "/d1/d2/d3", vfs.EventCreate)
+			Expect(fs.Symlink("f1", "/d1/d2/link")).To(Succeed())
+			ExpectEvent(w, "/d1/d2/link", vfs.EventCreate)
+			Expect(fs.RemoveAll("/d1/d2")).To(Succeed())
+			ExpectEvent(w, "/d1/d2", vfs.EventRemove)
+		})
+
+		It("stops on close", func() {
+			w, err := vfs.Watch(fs, "/d1", false)
+			Expect(err).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+			Expect(vfs.WriteFile(fs, "/d1/f2", nil, os.ModePerm)).To(Succeed())
+			_, ok := <-w.Events()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("rejects non-existing paths", func() {
+			_, err := vfs.Watch(fs, "/d2", false)
+			Expect(vfs.IsErrNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("locking", func() {
+		var f1, f2 vfs.File
+
+		BeforeEach(func() {
+			var err error
+			Expect(vfs.WriteFile(fs, "/f1", []byte("data"), os.ModePerm)).To(Succeed())
+			f1, err = fs.Open("/f1")
+			Expect(err).To(Succeed())
+			f2, err = fs.Open("/f1")
+			Expect(err).To(Succeed())
+		})
+		AfterEach(func() {
+			f1.Close()
+			f2.Close()
+		})
+
+		It("shares shared locks", func() {
+			Expect(vfs.Lock(f1, vfs.LockShared, false)).To(Succeed())
+			Expect(vfs.Lock(f2, vfs.LockShared, false)).To(Succeed())
+			Expect(vfs.IsErrWouldBlock(vfs.Lock(f2, vfs.LockExclusive, false))).To(BeTrue())
+		})
+
+		It("rejects conflicting locks", func() {
+			Expect(vfs.Lock(f1, vfs.LockExclusive, false)).To(Succeed())
+			Expect(vfs.IsErrWouldBlock(vfs.Lock(f2, vfs.LockShared, false))).To(BeTrue())
+			Expect(vfs.Unlock(f1)).To(Succeed())
+			Expect(vfs.Lock(f2, vfs.LockShared, false)).To(Succeed())
+		})
+
+		It("waits for a lock", func() {
+			Expect(vfs.Lock(f1, vfs.LockExclusive, false)).To(Succeed())
+			done := make(chan error)
+			go func() {
+				done <- vfs.Lock(f2, vfs.LockExclusive, true)
+			}()
+			Consistently(done, "100ms").ShouldNot(Receive())
+			Expect(f1.Close()).To(Succeed())
+			Eventually(done).Should(Receive(BeNil()))
+		})
+
+		It("locks byte ranges", func() {
+			Expect(vfs.LockRange(f1, 0, 2, vfs.LockExclusive, false)).To(Succeed())
+			Expect(vfs.LockRange(f2, 2, 0, vfs.LockExclusive, false)).To(Succeed())
+			Expect(vfs.IsErrWouldBlock(vfs.LockRange(f2, 1, 2, vfs.LockShared, false))).To(BeTrue())
+			Expect(vfs.UnlockRange(f1, 1, 1)).To(Succeed())
+			Expect(vfs.LockRange(f2, 1, 1, vfs.LockShared, false)).To(Succeed())
+			Expect(vfs.IsErrWouldBlock(vfs.LockRange(f2, 0, 1, vfs.LockShared, false))).To(BeTrue())
+		})
+	})
 })
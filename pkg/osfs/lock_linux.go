@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"io"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ vfs.RangeLockableFile = (*osFile)(nil)
+
+// LockRange acquires an advisory lock for a byte range using
+// open file description locks (fcntl(2) with F_OFD_SETLK).
+// They are bound to the file handle like flock(2) locks, but
+// are independent of locks acquired with Lock.
+// Like for fcntl(2), exclusive locks require a file
+// opened for writing.
+func (f *osFile) LockRange(offset, length int64, t vfs.LockType, wait bool) error {
+	lk := unix.Flock_t{
+		Type:   unix.F_RDLCK,
+		Whence: io.SeekStart,
+		Start:  offset,
+		Len:    length,
+	}
+	if t == vfs.LockExclusive {
+		lk.Type = unix.F_WRLCK
+	}
+	cmd := unix.F_OFD_SETLK
+	if wait {
+		cmd = unix.F_OFD_SETLKW
+	}
+	return f.control("lock", func(fd uintptr) error {
+		return unix.FcntlFlock(fd, cmd, &lk)
+	})
+}
+
+// UnlockRange releases an advisory lock acquired with LockRange.
+func (f *osFile) UnlockRange(offset, length int64) error {
+	lk := unix.Flock_t{
+		Type:   unix.F_UNLCK,
+		Whence: io.SeekStart,
+		Start:  offset,
+		Len:    length,
+	}
+	return f.control("unlock", func(fd uintptr) error {
+		return unix.FcntlFlock(fd, unix.F_OFD_SETLK, &lk)
+	})
+}
@@ -0,0 +1,80 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("locking", func() {
+	var fs vfs.FileSystem
+	var f1, f2 vfs.File
+
+	BeforeEach(func() {
+		var err error
+		fs, err = NewTempFileSystem()
+		Expect(err).To(Succeed())
+		Expect(vfs.WriteFile(fs, "/f1", []byte("data"), os.ModePerm)).To(Succeed())
+		f1, err = fs.OpenFile("/f1", os.O_RDWR, 0)
+		Expect(err).To(Succeed())
+		f2, err = fs.OpenFile("/f1", os.O_RDWR, 0)
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		f1.Close()
+		f2.Close()
+		vfs.Cleanup(fs)
+	})
+
+	It("locks files with flock", func() {
+		Expect(vfs.Lock(f1, vfs.LockShared, false)).To(Succeed())
+		Expect(vfs.Lock(f2, vfs.LockShared, false)).To(Succeed())
+		Expect(vfs.IsErrWouldBlock(vfs.Lock(f2, vfs.LockExclusive, false))).To(BeTrue())
+		Expect(vfs.Unlock(f1)).To(Succeed())
+		Expect(vfs.Lock(f2, vfs.LockExclusive, false)).To(Succeed())
+	})
+
+	It("waits for a lock", func() {
+		Expect(vfs.Lock(f1, vfs.LockExclusive, false)).To(Succeed())
+		done := make(chan error)
+		go func() {
+			done <- vfs.Lock(f2, vfs.LockExclusive, true)
+		}()
+		Consistently(done, "100ms").ShouldNot(Receive())
+		Expect(f1.Close()).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("locks byte ranges", func() {
+		Expect(vfs.LockRange(f1, 0, 2, vfs.LockExclusive, false)).To(Succeed())
+		Expect(vfs.LockRange(f2, 2, 0, vfs.LockExclusive, false)).To(Succeed())
+		Expect(vfs.IsErrWouldBlock(vfs.LockRange(f2, 1, 2, vfs.LockShared, false))).To(BeTrue())
+		Expect(vfs.UnlockRange(f1, 1, 1)).To(Succeed())
+		Expect(vfs.LockRange(f2, 1, 1, vfs.LockShared, false)).To(Succeed())
+	})
+})
@@ -0,0 +1,79 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ vfs.LockableFile = (*osFile)(nil)
+
+// Lock acquires an advisory lock using flock(2).
+func (f *osFile) Lock(t vfs.LockType, wait bool) error {
+	how := unix.LOCK_SH
+	if t == vfs.LockExclusive {
+		how = unix.LOCK_EX
+	}
+	if !wait {
+		how |= unix.LOCK_NB
+	}
+	return f.control("lock", func(fd uintptr) error {
+		return unix.Flock(int(fd), how)
+	})
+}
+
+// Unlock releases an advisory lock acquired with Lock.
+func (f *osFile) Unlock() error {
+	return f.control("unlock", func(fd uintptr) error {
+		return unix.Flock(int(fd), unix.LOCK_UN)
+	})
+}
+
+func (f *osFile) control(op string, fn func(fd uintptr) error) error {
+	c, err := f.File.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var lerr error
+	err = c.Control(func(fd uintptr) {
+		for {
+			lerr = fn(fd)
+			if lerr != unix.EINTR {
+				break
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if lerr != nil {
+		if lerr == unix.EWOULDBLOCK || lerr == unix.EAGAIN {
+			lerr = vfs.ErrWouldBlock
+		}
+		return &os.PathError{Op: op, Path: f.Name(), Err: lerr}
+	}
+	return nil
+}
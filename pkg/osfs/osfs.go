@@ -31,6 +31,10 @@ import (
 
 var OsFs = &osFileSystem{}
 
+var _ vfs.OwnershipFileSystem = (*osFileSystem)(nil)
+var _ vfs.LinkFileSystem = (*osFileSystem)(nil)
+var _ vfs.XattrFileSystem = (*osFileSystem)(nil)
+
 type osFile struct {
 	*os.File
 }
@@ -174,6 +178,14 @@ func (osFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error
 	return os.Chtimes(name, atime, mtime)
 }
 
+func (osFileSystem) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+func (osFileSystem) Lchown(name string, uid, gid int) error {
+	return os.Lchown(name, uid, gid)
+}
+
 func (osFileSystem) Lstat(name string) (os.FileInfo, error) {
 	return os.Lstat(name)
 }
@@ -182,6 +194,10 @@ func (osFileSystem) Symlink(oldname, newname string) error {
 	return os.Symlink(oldname, newname)
 }
 
+func (osFileSystem) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
 func (osFileSystem) Readlink(name string) (string, error) {
 	return os.Readlink(name)
 }
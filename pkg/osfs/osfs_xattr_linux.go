@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func (osFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	return getxattr("getxattr", name, attr, unix.Getxattr)
+}
+
+func (osFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	return wrapErr("setxattr", name, unix.Setxattr(name, attr, data, flags))
+}
+
+func (osFileSystem) Listxattr(name string) ([]string, error) {
+	return listxattr("listxattr", name, unix.Listxattr)
+}
+
+func (osFileSystem) Removexattr(name, attr string) error {
+	return wrapErr("removexattr", name, unix.Removexattr(name, attr))
+}
+
+func (osFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	return getxattr("lgetxattr", name, attr, unix.Lgetxattr)
+}
+
+func (osFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return wrapErr("lsetxattr", name, unix.Lsetxattr(name, attr, data, flags))
+}
+
+func (osFileSystem) Llistxattr(name string) ([]string, error) {
+	return listxattr("llistxattr", name, unix.Llistxattr)
+}
+
+func (osFileSystem) Lremovexattr(name, attr string) error {
+	return wrapErr("lremovexattr", name, unix.Lremovexattr(name, attr))
+}
+
+func wrapErr(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// fetch calls a size probing system call until the
+// provided buffer is sufficient to hold the result.
+func fetch(call func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := call(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return []byte{}, nil
+		}
+		buf := make([]byte, size)
+		size, err = call(buf)
+		if err == unix.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	}
+}
+
+func getxattr(op, name, attr string, call func(string, string, []byte) (int, error)) ([]byte, error) {
+	data, err := fetch(func(dest []byte) (int, error) { return call(name, attr, dest) })
+	if err != nil {
+		return nil, wrapErr(op, name, err)
+	}
+	return data, nil
+}
+
+func listxattr(op, name string, call func(string, []byte) (int, error)) ([]string, error) {
+	data, err := fetch(func(dest []byte) (int, error) { return call(name, dest) })
+	if err != nil {
+		return nil, wrapErr(op, name, err)
+	}
+	list := []string{}
+	for _, n := range strings.Split(string(data), "\x00") {
+		if n != "" {
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,62 @@
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// Extended attributes are natively supported on Linux, only.
+
+func (osFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	return nil, &os.PathError{Op: "getxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	return &os.PathError{Op: "setxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Listxattr(name string) ([]string, error) {
+	return nil, &os.PathError{Op: "listxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Removexattr(name, attr string) error {
+	return &os.PathError{Op: "removexattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	return nil, &os.PathError{Op: "lgetxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return &os.PathError{Op: "lsetxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Llistxattr(name string) ([]string, error) {
+	return nil, &os.PathError{Op: "llistxattr", Path: name, Err: vfs.ErrNotSupported}
+}
+
+func (osFileSystem) Lremovexattr(name, attr string) error {
+	return &os.PathError{Op: "lremovexattr", Path: name, Err: vfs.ErrNotSupported}
+}
@@ -27,7 +27,7 @@ import (
 )
 
 type tempfs struct {
-	vfs.FileSystem
+	*projectionfs.ProjectionFileSystem
 	dir string
 }
 
@@ -41,8 +41,9 @@ func NewTempFileSystem() (vfs.FileSystem, error) {
 	fs, err := projectionfs.New(New(), dir)
 	if err != nil {
 		os.Remove(dir)
+		return nil, err
 	}
-	return &tempfs{fs, dir}, err
+	return &tempfs{fs.(*projectionfs.ProjectionFileSystem), dir}, nil
 }
 
 func (t *tempfs) Cleanup() error {
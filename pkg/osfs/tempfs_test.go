@@ -59,4 +59,22 @@ var _ = Describe("temp", func() {
 		Expect(fs.Mkdir(d, os.ModePerm)).To(Succeed())
 		ExpectFolders(fs, path, []string{"d1"}, nil)
 	})
+	It("writes files atomically", func() {
+		Expect(fs.WriteFile("/f1", []byte("old"), 0o640)).To(Succeed())
+		Expect(fs.WriteFileAtomic("/f1", []byte("new"), os.ModePerm)).To(Succeed())
+		ExpectFileContent(fs, "/f1", "new")
+		fi, err := fs.Stat("/f1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+		ExpectFolders(fs, "/", []string{"f1"}, nil)
+	})
+	It("applies the umask to new files written atomically", func() {
+		Expect(fs.WriteFile("/ref", []byte("ref"), 0o666)).To(Succeed())
+		Expect(fs.WriteFileAtomic("/f1", []byte("new"), 0o666)).To(Succeed())
+		ref, err := fs.Stat("/ref")
+		Expect(err).To(Succeed())
+		fi, err := fs.Stat("/f1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(ref.Mode().Perm()))
+	})
 })
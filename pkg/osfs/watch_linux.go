@@ -0,0 +1,182 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/mandelsoft/filepath/pkg/filepath"
+	"golang.org/x/sys/unix"
+
+	"github.com/mandelsoft/vfs/pkg/utils"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ vfs.WatchableFileSystem = (*osFileSystem)(nil)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_DELETE | unix.IN_DELETE_SELF |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_MOVE_SELF
+
+// Watch watches a path using inotify. For recursive watches,
+// all sub directories are watched, including new ones.
+func (osFileSystem) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	path := filepath.Clean(name)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	file := os.NewFile(uintptr(fd), "inotify")
+	w := &inotifyWatcher{
+		Watcher:   utils.NewWatcher(file.Close),
+		file:      file,
+		fd:        fd,
+		recursive: recursive && fi.IsDir(),
+		dirs:      map[int]string{},
+	}
+	w.root, err = w.add(path)
+	if err != nil {
+		w.Close()
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	if w.recursive {
+		w.addTree(path, false)
+	}
+	go w.read()
+	return w, nil
+}
+
+type inotifyWatcher struct {
+	*utils.Watcher
+	file      *os.File
+	fd        int
+	recursive bool
+
+	lock sync.Mutex
+	root int
+	dirs map[int]string
+}
+
+func (w *inotifyWatcher) add(path string) (int, error) {
+	wd, err := unix.InotifyAddWatch(w.fd, path, inotifyMask)
+	if err != nil {
+		return -1, err
+	}
+	w.lock.Lock()
+	w.dirs[wd] = path
+	w.lock.Unlock()
+	return wd, nil
+}
+
+// addTree adds watches for all sub directories of an already
+// watched directory. Entries created before the watch has been
+// established are optionally reported.
+// Directories vanished in the meantime are ignored.
+func (w *inotifyWatcher) addTree(path string, report bool) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		p := filepath.Join(path, e.Name())
+		if report {
+			w.Send(vfs.Event{Path: p, Op: vfs.EventCreate})
+		}
+		if e.IsDir() {
+			if _, err := w.add(p); err == nil {
+				w.addTree(p, report)
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 4096*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			w.Close()
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + unix.SizeofInotifyEvent
+			offset = start + int(raw.Len)
+			name := string(buf[start:offset])
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			w.handle(int(raw.Wd), raw.Mask, name)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(wd int, mask uint32, name string) {
+	w.lock.Lock()
+	dir, ok := w.dirs[wd]
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.dirs, wd)
+	}
+	w.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	} else if wd != w.root {
+		// already reported for the entry of the parent directory
+		return
+	}
+
+	var op vfs.EventOp
+	if mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		op |= vfs.EventCreate
+	}
+	if mask&unix.IN_MODIFY != 0 {
+		op |= vfs.EventWrite
+	}
+	if mask&(unix.IN_DELETE|unix.IN_DELETE_SELF) != 0 {
+		op |= vfs.EventRemove
+	}
+	if mask&(unix.IN_MOVED_FROM|unix.IN_MOVE_SELF) != 0 {
+		op |= vfs.EventRename
+	}
+	if mask&unix.IN_ATTRIB != 0 {
+		op |= vfs.EventChmod
+	}
+	if op != 0 {
+		w.Send(vfs.Event{Path: path, Op: op})
+	}
+	if w.recursive && op&vfs.EventCreate != 0 && mask&unix.IN_ISDIR != 0 {
+		if _, err := w.add(path); err == nil {
+			w.addTree(path, true)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("watch", func() {
+	var fs vfs.FileSystem
+	var root string
+
+	BeforeEach(func() {
+		t, err := NewTempFileSystem()
+		Expect(err).To(Succeed())
+		fs = t
+		root = t.(*tempfs).Root()
+		Expect(fs.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		vfs.Cleanup(fs)
+	})
+
+	It("reports changes with inotify", func() {
+		w, err := vfs.Watch(OsFs, root+"/d1", false)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/f1", []byte("data"), os.ModePerm)).To(Succeed())
+		ExpectEvent(w, root+"/d1/f1", vfs.EventCreate)
+		ExpectEvent(w, root+"/d1/f1", vfs.EventWrite)
+		Expect(fs.Chmod("/d1/f1", 0o600)).To(Succeed())
+		ExpectEvent(w, root+"/d1/f1", vfs.EventChmod)
+		Expect(fs.Rename("/d1/f1", "/d1/f2")).To(Succeed())
+		ExpectEvent(w, root+"/d1/f1", vfs.EventRename)
+		ExpectEvent(w, root+"/d1/f2", vfs.EventCreate)
+		Expect(fs.Remove("/d1/f2")).To(Succeed())
+		ExpectEvent(w, root+"/d1/f2", vfs.EventRemove)
+	})
+
+	It("reports changes recursively in projected filesystem", func() {
+		w, err := vfs.Watch(fs, "/d1", true)
+		Expect(err).To(Succeed())
+		defer w.Close()
+
+		Expect(vfs.WriteFile(fs, "/d1/d2/f1", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/d2/f1", vfs.EventCreate)
+		Expect(fs.MkdirAll("/d1/d3/d4", os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/d3", vfs.EventCreate)
+		Expect(vfs.WriteFile(fs, "/d1/d3/d4/f1", nil, os.ModePerm)).To(Succeed())
+		ExpectEvent(w, "/d1/d3/d4/f1", vfs.EventCreate)
+	})
+
+	It("stops on close", func() {
+		w, err := vfs.Watch(fs, "/d1", false)
+		Expect(err).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+		for range w.Events() {
+		}
+	})
+})
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package osfs
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("xattr", func() {
+	var fs vfs.FileSystem
+
+	BeforeEach(func() {
+		t, err := NewTempFileSystem()
+		Expect(err).To(Succeed())
+		fs = t
+		ExpectFileCreate(fs, "f1", nil, nil)
+		err = vfs.Setxattr(fs, "f1", "user.test", []byte("value"), 0)
+		if vfs.IsErrNotSupported(err) {
+			Skip("extended attributes not supported")
+		}
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		vfs.Cleanup(fs)
+	})
+
+	It("gets attribute", func() {
+		Expect(vfs.Getxattr(fs, "f1", "user.test")).To(Equal([]byte("value")))
+		Expect(vfs.Listxattr(fs, "f1")).To(ContainElement("user.test"))
+	})
+
+	It("removes attribute", func() {
+		Expect(vfs.Removexattr(fs, "f1", "user.test")).To(Succeed())
+		_, err := vfs.Getxattr(fs, "f1", "user.test")
+		Expect(vfs.IsErrNoAttr(err)).To(BeTrue())
+	})
+})
@@ -57,5 +57,10 @@ var _ = Describe("readonly filesystem", func() {
 			Expect(mem.Mkdir("/d1/test", os.ModePerm)).To(Succeed())
 			ExpectFolders(fs, "/d1", []string{"d1d1", "test"}, nil)
 		})
+
+		It("chown", func() {
+			Expect(vfs.Chown(fs, "/d1", 0, 0)).To(Equal(ErrReadOnly))
+			Expect(vfs.Lchown(fs, "/d1", 0, 0)).To(Equal(ErrReadOnly))
+		})
 	})
 })
@@ -30,6 +30,10 @@ type readonlyFileSystem struct {
 }
 
 var _ vfs.FileSystem = &readonlyFileSystem{}
+var _ vfs.OwnershipFileSystem = &readonlyFileSystem{}
+var _ vfs.LinkFileSystem = &readonlyFileSystem{}
+var _ vfs.XattrFileSystem = &readonlyFileSystem{}
+var _ vfs.WatchableFileSystem = &readonlyFileSystem{}
 
 func New(fs vfs.FileSystem) vfs.FileSystem {
 	return &readonlyFileSystem{fs}
@@ -58,6 +62,10 @@ func (r *readonlyFileSystem) Symlink(oldname, newname string) error {
 	return ErrReadOnly
 }
 
+func (r *readonlyFileSystem) Link(oldname, newname string) error {
+	return ErrReadOnly
+}
+
 func (r *readonlyFileSystem) Rename(oldname, newname string) error {
 	return ErrReadOnly
 }
@@ -78,4 +86,48 @@ func (r *readonlyFileSystem) Chtimes(path string, atime time.Time, mtime time.Ti
 	return ErrReadOnly
 }
 
+func (r *readonlyFileSystem) Chown(path string, uid, gid int) error {
+	return ErrReadOnly
+}
+
+func (r *readonlyFileSystem) Lchown(path string, uid, gid int) error {
+	return ErrReadOnly
+}
+
+func (r *readonlyFileSystem) Getxattr(path, attr string) ([]byte, error) {
+	return vfs.Getxattr(r.FileSystem, path, attr)
+}
+
+func (r *readonlyFileSystem) Setxattr(path, attr string, data []byte, flags int) error {
+	return ErrReadOnly
+}
+
+func (r *readonlyFileSystem) Listxattr(path string) ([]string, error) {
+	return vfs.Listxattr(r.FileSystem, path)
+}
+
+func (r *readonlyFileSystem) Removexattr(path, attr string) error {
+	return ErrReadOnly
+}
+
+func (r *readonlyFileSystem) Lgetxattr(path, attr string) ([]byte, error) {
+	return vfs.Lgetxattr(r.FileSystem, path, attr)
+}
+
+func (r *readonlyFileSystem) Lsetxattr(path, attr string, data []byte, flags int) error {
+	return ErrReadOnly
+}
+
+func (r *readonlyFileSystem) Llistxattr(path string) ([]string, error) {
+	return vfs.Llistxattr(r.FileSystem, path)
+}
+
+func (r *readonlyFileSystem) Lremovexattr(path, attr string) error {
+	return ErrReadOnly
+}
+
 var ErrReadOnly = vfs.ErrReadOnly
+
+func (r *readonlyFileSystem) Watch(path string, recursive bool) (vfs.Watcher, error) {
+	return vfs.Watch(r.FileSystem, path, recursive)
+}
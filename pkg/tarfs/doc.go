@@ -0,0 +1,25 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package tarfs provides a virtual filesystem for the content of a
+// tar archive and functions to extract tar archives into and export them
+// from any virtual filesystem.
+// Tar archives may be gzip compressed. Regular files, directories,
+// symbolic links and hard links are supported, as well as ownership and
+// extended attributes stored as PAX records. Other file types are ignored.
+package tarfs
@@ -0,0 +1,399 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package tarfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// PAXXattrPrefix is the prefix of PAX records used to store
+// extended attributes.
+const PAXXattrPrefix = "SCHILY.xattr."
+
+// New provides a memory based filesystem with the content of
+// the tar archive read from r.
+func New(r io.Reader) (vfs.FileSystem, error) {
+	fs := memoryfs.New()
+	err := Extract(fs, r)
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// NewByPath provides a memory based filesystem with the content of
+// the tar archive found at the given path of a virtual filesystem.
+// If no filesystem is given, the operating system filesystem is used.
+func NewByPath(fs vfs.FileSystem, path string) (vfs.FileSystem, error) {
+	var file io.ReadCloser
+	var err error
+
+	if fs == nil {
+		file, err = os.Open(path)
+	} else {
+		file, err = fs.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return New(file)
+}
+
+// WriteFile writes the content of the filesystem fs as tar archive
+// to the given path of the filesystem dstfs. If no target filesystem is
+// given, the operating system filesystem is used.
+// The archive is gzip compressed, if the path has the suffix .gz or .tgz.
+func WriteFile(fs vfs.FileSystem, dstfs vfs.FileSystem, path string, perm os.FileMode) (err error) {
+	var file io.WriteCloser
+
+	if dstfs == nil {
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	} else {
+		file, err = dstfs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if !strings.HasSuffix(path, ".gz") && !strings.HasSuffix(path, ".tgz") {
+		return Export(fs, file)
+	}
+	zw := gzip.NewWriter(file)
+	err = Export(fs, zw)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// decompress provides a reader for the uncompressed
+// content of a potentially gzip compressed stream.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// Extract extracts the tar archive read from r into the given filesystem.
+// The archive may be gzip compressed. Ownership and extended attributes
+// are preserved, if supported by the filesystem and permitted
+// for the actual process. Entries are always created inside the
+// filesystem root, entries with a symbolic link in their parent
+// path are rejected.
+func Extract(fs vfs.FileSystem, r io.Reader) error {
+	r, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+
+	type dirinfo struct {
+		path  string
+		mode  os.FileMode
+		atime time.Time
+		mtime time.Time
+	}
+	var dirs []dirinfo
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p, err := extract(fs, tr, h)
+		if err != nil {
+			return err
+		}
+		if p != "" && h.Typeflag == tar.TypeDir {
+			dirs = append(dirs, dirinfo{p, os.FileMode(h.Mode).Perm(), atime(h), h.ModTime})
+		}
+	}
+	// set modes and times of directories after their content has been
+	// created, a read-only directory must not prevent the creation
+	// of its content.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := fs.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+		if err := fs.Chtimes(dirs[i].path, dirs[i].atime, dirs[i].mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func atime(h *tar.Header) time.Time {
+	if h.AccessTime.IsZero() {
+		return h.ModTime
+	}
+	return h.AccessTime
+}
+
+// extract creates a filesystem entry for a tar header.
+// It returns the path of the created entry, or an empty
+// string, if the entry has been ignored.
+func extract(fs vfs.FileSystem, tr *tar.Reader, h *tar.Header) (string, error) {
+	p := path.Clean(vfs.PathSeparatorString + h.Name)
+	mode := os.FileMode(h.Mode).Perm()
+
+	if p != vfs.PathSeparatorString {
+		if err := checkParents(fs, p); err != nil {
+			return "", err
+		}
+		if err := fs.MkdirAll(path.Dir(p), 0o755); err != nil {
+			return "", err
+		}
+		if err := remove(fs, p, h.Typeflag == tar.TypeDir); err != nil {
+			return "", err
+		}
+	}
+
+	switch h.Typeflag {
+	case tar.TypeDir:
+		// the final mode is set by Extract
+		if err := fs.MkdirAll(p, mode|0o700); err != nil {
+			return "", err
+		}
+		if err := fs.Chmod(p, mode|0o700); err != nil {
+			return "", err
+		}
+	case tar.TypeReg:
+		f, err := fs.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := fs.Chmod(p, mode); err != nil {
+			return "", err
+		}
+	case tar.TypeSymlink:
+		if err := fs.Symlink(h.Linkname, p); err != nil {
+			return "", err
+		}
+	case tar.TypeLink:
+		old := path.Clean(vfs.PathSeparatorString + h.Linkname)
+		if err := checkParents(fs, old); err != nil {
+			return "", err
+		}
+		if err := vfs.Link(fs, old, p); err != nil {
+			return "", err
+		}
+		// a hard link shares the metadata of its target
+		return p, nil
+	default:
+		return "", nil
+	}
+
+	if err := vfs.Lchown(fs, p, h.Uid, h.Gid); err != nil && !ignore(err) {
+		return "", err
+	}
+	for k, v := range h.PAXRecords {
+		if strings.HasPrefix(k, PAXXattrPrefix) {
+			err := vfs.Lsetxattr(fs, p, k[len(PAXXattrPrefix):], []byte(v), 0)
+			if err != nil && !ignore(err) {
+				return "", err
+			}
+		}
+	}
+	if h.Typeflag == tar.TypeReg {
+		if err := fs.Chtimes(p, atime(h), h.ModTime); err != nil {
+			return "", err
+		}
+	}
+	return p, nil
+}
+
+// ignore reports whether an error setting metadata can be ignored,
+// because it is not supported by the filesystem or requires
+// privileges not available for the actual process.
+func ignore(err error) bool {
+	return vfs.IsErrNotSupported(err) || os.IsPermission(err)
+}
+
+// remove removes an existing entry, which is replaced by a later
+// entry of an archive. Existing directories are kept for directory
+// entries.
+func remove(fs vfs.FileSystem, p string, dir bool) error {
+	fi, err := fs.Lstat(p)
+	if err != nil {
+		if vfs.IsErrNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.IsDir() {
+		if dir {
+			return nil
+		}
+		return fs.RemoveAll(p)
+	}
+	return fs.Remove(p)
+}
+
+// checkParents rejects paths with symbolic links created by earlier
+// entries of an archive as parent directories, which might be used
+// to write outside of the extraction root.
+func checkParents(fs vfs.FileSystem, p string) error {
+	for dir := path.Dir(p); dir != vfs.PathSeparatorString; dir = path.Dir(dir) {
+		fi, err := fs.Lstat(dir)
+		if err != nil {
+			if vfs.IsErrNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return vfs.NewPathError("extract", p, errors.New("symbolic link in path"))
+		}
+	}
+	return nil
+}
+
+// Export writes the content of the given filesystem as tar archive to w.
+// Files with multiple hard links are stored once and referenced by
+// link entries. Ownership and extended attributes are preserved,
+// if supported by the filesystem.
+func Export(fs vfs.FileSystem, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	var links []link
+
+	err := vfs.Walk(fs, vfs.PathSeparatorString, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == vfs.PathSeparatorString {
+			return nil
+		}
+		h, err := header(fs, p, fi)
+		if err != nil || h == nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeReg {
+			if n, ok := vfs.Nlink(fi); ok && n > 1 {
+				for _, l := range links {
+					if vfs.SameFile(l.info, fi) {
+						h.Typeflag = tar.TypeLink
+						h.Linkname = l.name
+						h.Size = 0
+						break
+					}
+				}
+				if h.Typeflag == tar.TypeReg {
+					links = append(links, link{h.Name, fi})
+				}
+			}
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			return nil
+		}
+		f, err := fs.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+type link struct {
+	name string
+	info os.FileInfo
+}
+
+// header provides the tar header for a filesystem entry.
+// It returns nil for unsupported file types.
+func header(fs vfs.FileSystem, p string, fi os.FileInfo) (*tar.Header, error) {
+	var target string
+	var err error
+
+	switch {
+	case fi.Mode().IsRegular(), fi.IsDir():
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err = fs.Readlink(p)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	h, err := tar.FileInfoHeader(fi, target)
+	if err != nil {
+		return nil, err
+	}
+	h.Name = p[1:]
+	if fi.IsDir() {
+		h.Name += "/"
+	}
+	if uid, gid, ok := vfs.Owner(fi); ok && uid >= 0 && gid >= 0 {
+		h.Uid, h.Gid = uid, gid
+	}
+	attrs, err := vfs.Llistxattr(fs, p)
+	if err != nil && !vfs.IsErrNotSupported(err) {
+		return nil, err
+	}
+	for _, a := range attrs {
+		v, err := vfs.Lgetxattr(fs, p, a)
+		if err != nil {
+			return nil, err
+		}
+		if h.PAXRecords == nil {
+			h.PAXRecords = map[string]string{}
+		}
+		h.PAXRecords[PAXXattrPrefix+a] = string(v)
+	}
+	return h, nil
+}
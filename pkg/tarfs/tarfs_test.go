@@ -0,0 +1,243 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package tarfs_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/tarfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var modtime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var _ = Describe("tar filesystem", func() {
+	var fs vfs.VFS
+
+	BeforeEach(func() {
+		fs = vfs.New(memoryfs.New())
+		Expect(fs.MkdirAll("/d1/d2", 0o750)).To(Succeed())
+		Expect(fs.WriteFile("/d1/f1", []byte("file1"), 0o600)).To(Succeed())
+		Expect(fs.WriteFile("/d1/d2/f2", []byte("file2"), 0o644)).To(Succeed())
+		Expect(fs.Symlink("d2/f2", "/d1/link")).To(Succeed())
+		Expect(fs.Link("/d1/f1", "/hard")).To(Succeed())
+		Expect(fs.Chown("/d1/f1", 4711, 42)).To(Succeed())
+		Expect(fs.Setxattr("/d1/d2/f2", "user.test", []byte("value"), 0)).To(Succeed())
+		Expect(fs.Chtimes("/d1/d2/f2", modtime, modtime)).To(Succeed())
+		Expect(fs.Chtimes("/d1", modtime, modtime)).To(Succeed())
+	})
+
+	check := func(result vfs.FileSystem) {
+		ExpectFolders(result, "/", []string{"d1", "hard"}, nil)
+		ExpectFolders(result, "/d1", []string{"d2", "f1", "link"}, nil)
+		ExpectFileContent(result, "/d1/f1", "file1")
+		ExpectFileContent(result, "/d1/d2/f2", "file2")
+		ExpectFileContent(result, "/hard", "file1")
+		Expect(result.Readlink("/d1/link")).To(Equal("d2/f2"))
+
+		fi, err := result.Stat("/d1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode() & os.ModePerm).To(Equal(os.FileMode(0o750)))
+		Expect(fi.ModTime().Equal(modtime)).To(BeTrue())
+
+		fi, err = result.Stat("/d1/f1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode() & os.ModePerm).To(Equal(os.FileMode(0o600)))
+		uid, gid, ok := vfs.Owner(fi)
+		Expect(ok).To(BeTrue())
+		Expect([]int{uid, gid}).To(Equal([]int{4711, 42}))
+		n, _ := vfs.Nlink(fi)
+		Expect(n).To(Equal(uint64(2)))
+		hfi, err := result.Stat("/hard")
+		Expect(err).To(Succeed())
+		Expect(vfs.SameFile(fi, hfi)).To(BeTrue())
+
+		fi, err = result.Stat("/d1/d2/f2")
+		Expect(err).To(Succeed())
+		Expect(fi.ModTime().Equal(modtime)).To(BeTrue())
+		Expect(vfs.Getxattr(result, "/d1/d2/f2", "user.test")).To(Equal([]byte("value")))
+	}
+
+	It("exports and loads archive", func() {
+		var buf bytes.Buffer
+		Expect(tarfs.Export(fs, &buf)).To(Succeed())
+		result, err := tarfs.New(&buf)
+		Expect(err).To(Succeed())
+		check(result)
+	})
+
+	It("writes and reads compressed archive", func() {
+		dst := memoryfs.New()
+		Expect(tarfs.WriteFile(fs, dst, "/archive.tgz", 0o644)).To(Succeed())
+		data, err := vfs.ReadFile(dst, "/archive.tgz")
+		Expect(err).To(Succeed())
+		Expect(data[:2]).To(Equal([]byte{0x1f, 0x8b}))
+		result, err := tarfs.NewByPath(dst, "/archive.tgz")
+		Expect(err).To(Succeed())
+		check(result)
+	})
+
+	It("writes hard links as link entries", func() {
+		var buf bytes.Buffer
+		Expect(tarfs.Export(fs, &buf)).To(Succeed())
+		tr := tar.NewReader(&buf)
+		types := map[string]byte{}
+		for {
+			h, err := tr.Next()
+			if err != nil {
+				break
+			}
+			types[h.Name] = h.Typeflag
+		}
+		Expect(types).To(Equal(map[string]byte{
+			"d1/":      tar.TypeDir,
+			"d1/d2/":   tar.TypeDir,
+			"d1/d2/f2": tar.TypeReg,
+			"d1/f1":    tar.TypeReg,
+			"d1/link":  tar.TypeSymlink,
+			"hard":     tar.TypeLink,
+		}))
+	})
+
+	Context("extract", func() {
+		var buf bytes.Buffer
+		var tw *tar.Writer
+
+		BeforeEach(func() {
+			buf.Reset()
+			tw = tar.NewWriter(&buf)
+		})
+
+		add := func(h *tar.Header, data string) {
+			h.Size = int64(len(data))
+			if h.Mode == 0 {
+				h.Mode = 0o644
+			}
+			Expect(tw.WriteHeader(h)).To(Succeed())
+			_, err := tw.Write([]byte(data))
+			Expect(err).To(Succeed())
+		}
+
+		It("handles PAX headers", func() {
+			long := strings.Repeat("long/", 30) + "file"
+			add(&tar.Header{Name: long, Typeflag: tar.TypeReg, Format: tar.FormatPAX, ModTime: modtime,
+				PAXRecords: map[string]string{"SCHILY.xattr.user.pax": "pax"}}, "content")
+			Expect(tw.Close()).To(Succeed())
+
+			result, err := tarfs.New(&buf)
+			Expect(err).To(Succeed())
+			ExpectFileContent(result, long, "content")
+			Expect(vfs.Getxattr(result, long, "user.pax")).To(Equal([]byte("pax")))
+		})
+
+		It("replaces entries and keeps paths inside", func() {
+			add(&tar.Header{Name: "../f1", Typeflag: tar.TypeReg}, "first")
+			add(&tar.Header{Name: "d1/../f1", Typeflag: tar.TypeReg}, "second")
+			add(&tar.Header{Name: "d2/f2", Typeflag: tar.TypeReg}, "implicit")
+			add(&tar.Header{Name: "fifo", Typeflag: tar.TypeFifo}, "")
+			Expect(tw.Close()).To(Succeed())
+
+			result, err := tarfs.New(&buf)
+			Expect(err).To(Succeed())
+			ExpectFolders(result, "/", []string{"d2", "f1"}, nil)
+			ExpectFileContent(result, "/f1", "second")
+			ExpectFileContent(result, "/d2/f2", "implicit")
+		})
+
+		It("does not write through symbolic links", func() {
+			add(&tar.Header{Name: "l", Typeflag: tar.TypeSymlink, Linkname: "/outside"}, "")
+			add(&tar.Header{Name: "l/f", Typeflag: tar.TypeReg}, "content")
+			Expect(tw.Close()).To(Succeed())
+
+			Expect(fs.Mkdir("/outside", 0o700)).To(Succeed())
+			err := tarfs.Extract(fs, &buf)
+			Expect(err).To(MatchError(ContainSubstring("symbolic link in path")))
+			Expect(fs.Exists("/outside/f")).To(BeFalse())
+		})
+
+		It("does not link through symbolic links", func() {
+			add(&tar.Header{Name: "l", Typeflag: tar.TypeSymlink, Linkname: "/outside"}, "")
+			add(&tar.Header{Name: "f", Typeflag: tar.TypeLink, Linkname: "l/f"}, "")
+			Expect(tw.Close()).To(Succeed())
+
+			Expect(fs.Mkdir("/outside", 0o700)).To(Succeed())
+			ExpectFileCreate(fs, "/outside/f", []byte("outside"), nil)
+			err := tarfs.Extract(fs, &buf)
+			Expect(err).To(MatchError(ContainSubstring("symbolic link in path")))
+		})
+
+		It("replaces symbolic links by directories", func() {
+			add(&tar.Header{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "/outside"}, "")
+			add(&tar.Header{Name: "d", Typeflag: tar.TypeDir, Mode: 0o777}, "")
+			add(&tar.Header{Name: "d/f", Typeflag: tar.TypeReg}, "content")
+			Expect(tw.Close()).To(Succeed())
+
+			Expect(fs.Mkdir("/outside", 0o700)).To(Succeed())
+			Expect(tarfs.Extract(fs, &buf)).To(Succeed())
+			ExpectFileContent(fs, "/d/f", "content")
+			ExpectFolders(fs, "/outside", nil, nil)
+			fi, err := fs.Stat("/outside")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode() & os.ModePerm).To(Equal(os.FileMode(0o700)))
+		})
+
+		It("creates content of read-only directories", func() {
+			add(&tar.Header{Name: "ro", Typeflag: tar.TypeDir, Mode: 0o555, ModTime: modtime}, "")
+			add(&tar.Header{Name: "ro/f", Typeflag: tar.TypeReg}, "content")
+			Expect(tw.Close()).To(Succeed())
+
+			Expect(tarfs.Extract(&readOnlyDirs{fs}, &buf)).To(Succeed())
+			ExpectFileContent(fs, "/ro/f", "content")
+			fi, err := fs.Stat("/ro")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode() & os.ModePerm).To(Equal(os.FileMode(0o555)))
+			Expect(fi.ModTime().Equal(modtime)).To(BeTrue())
+		})
+
+		It("rejects invalid archives", func() {
+			_, err := tarfs.New(strings.NewReader("no tar archive, but long enough to be checked as header"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+// readOnlyDirs rejects the creation of files in
+// directories without write permission.
+type readOnlyDirs struct {
+	vfs.FileSystem
+}
+
+func (fs *readOnlyDirs) OpenFile(name string, flags int, perm os.FileMode) (vfs.File, error) {
+	if flags&os.O_CREATE != 0 {
+		fi, err := fs.Stat(vfs.Dir(fs, name))
+		if err == nil && fi.Mode()&0o200 == 0 {
+			return nil, vfs.NewPathError("open", name, os.ErrPermission)
+		}
+	}
+	return fs.FileSystem.OpenFile(name, flags, perm)
+}
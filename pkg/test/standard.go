@@ -58,7 +58,7 @@ func StandardTest(creator func() vfs.FileSystem) bool {
 			Expect(fs.Mkdir("d1", os.ModePerm)).To(BeNil())
 			Expect(fs.Mkdir("d1/d2", os.ModePerm)).To(BeNil())
 			ExpectFolders(fs, "/d1", []string{"d2"}, nil)
-			Expect(fs.Remove("/d1")).To(Equal(&os.PathError{"remove", "/d1", vfs.ErrNotEmpty}))
+			Expect(fs.Remove("/d1")).To(Equal(&os.PathError{Op: "remove", Path: "/d1", Err: vfs.ErrNotEmpty}))
 			Expect(fs.Remove("/d1/d2")).To(Succeed())
 			ExpectFolders(fs, "/d1", []string{}, nil)
 		})
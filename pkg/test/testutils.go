@@ -20,10 +20,13 @@ package test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
+	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/mandelsoft/vfs/pkg/vfs"
@@ -140,3 +143,32 @@ func ExpectErr(args ...interface{}) {
 func ExpectSucceeded(args ...interface{}) {
 	Expect(args[len(args)-1]).To(Succeed())
 }
+
+// ExpectEvent waits for an event for the given path
+// including the given operation. Other events are skipped.
+func ExpectEvent(w vfs.Watcher, path string, op vfs.EventOp) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-w.Events():
+			ExpectWithOffset(1, ok).To(BeTrue(), "watcher closed")
+			if e.Path == path && e.Op.Has(op) {
+				return
+			}
+		case <-timeout:
+			Fail(fmt.Sprintf("no event %s %s", op, path), 1)
+			return
+		}
+	}
+}
+
+// ExpectNoEvent checks that there are no pending events.
+func ExpectNoEvent(w vfs.Watcher) {
+	select {
+	case e, ok := <-w.Events():
+		if ok {
+			Fail(fmt.Sprintf("unexpected event %s", e), 1)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+}
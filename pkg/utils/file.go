@@ -43,6 +43,38 @@ type FileData interface {
 	Del(name string) error
 }
 
+// FileDataOwnership is an optional interface for FileData
+// implementations able to keep the ownership of a file.
+type FileDataOwnership interface {
+	Owner() (uid, gid int)
+	SetOwner(uid, gid int)
+}
+
+// FileDataLinks is an optional interface for FileData
+// implementations supporting hard links. Such an
+// implementation allows to add the same FileData to
+// multiple directories and keeps track of the number of
+// directory entries referring to it.
+type FileDataLinks interface {
+	Links() int
+}
+
+// FileDataXattr is an optional interface for FileData
+// implementations able to keep extended attributes.
+type FileDataXattr interface {
+	GetXattr(attr string) ([]byte, bool)
+	SetXattr(attr string, data []byte)
+	ListXattr() []string
+	RemoveXattr(attr string) bool
+}
+
+// FileDataSize is an optional interface for FileData
+// implementations able to provide the size of a file
+// without accessing its content.
+type FileDataSize interface {
+	Size() int64
+}
+
 type File struct {
 	// atomic requires 64-bit alignment for struct field access
 	offset       int64
@@ -51,6 +83,8 @@ type File struct {
 	readOnly     bool
 	fileData     FileData
 	name         string
+	// notify reports changes of the file content.
+	notify func(op vfs.EventOp)
 }
 
 var _ vfs.File = &File{}
@@ -72,6 +106,7 @@ func (f *File) Close() error {
 	f.fileData.Lock()
 	f.closed = true
 	f.fileData.Unlock()
+	locks.releaseAll(f.fileData, f)
 	return nil
 }
 
@@ -80,7 +115,7 @@ func (f *File) Name() string {
 }
 
 func (f *File) Stat() (os.FileInfo, error) {
-	return NewFileInfo(f.name, f.fileData), nil
+	return NewFileInfo(vfs.Base(nil, f.name), f.fileData), nil
 }
 
 func (f *File) Sync() error {
@@ -130,7 +165,7 @@ func (f *File) Readdir(count int) (files []os.FileInfo, err error) {
 	}
 	f.readDirCount += outLength
 
-	return files, err
+	return files[:outLength], err
 }
 
 func (f *File) Readdirnames(n int) (names []string, err error) {
@@ -145,34 +180,36 @@ func (f *File) Readdirnames(n int) (names []string, err error) {
 func (f *File) Read(buf []byte) (int, error) {
 	f.fileData.Lock()
 	defer f.fileData.Unlock()
-	n, err := f.read(buf, f.offset, io.ErrUnexpectedEOF)
+	n, err := f.read(buf, f.offset)
 	f.offset += int64(n)
 	return n, err
 }
 
-func (f *File) read(b []byte, offset int64, err error) (int, error) {
+func (f *File) read(b []byte, offset int64) (int, error) {
 	if f.closed == true {
 		return 0, ErrFileClosed
 	}
 	data := f.fileData.Data()
-	if len(b) > 0 && int(offset) == len(data) {
-		return 0, io.EOF
-	}
-	if int(f.offset) > len(data) {
-		return 0, err
-	}
-	n := len(b)
-	if len(data)-int(offset) < len(b) {
-		n = len(data) - int(offset)
+	if offset >= int64(len(data)) {
+		if len(b) > 0 {
+			return 0, io.EOF
+		}
+		return 0, nil
 	}
-	copy(b, data[offset:offset+int64(n)])
-	return n, nil
+	return copy(b, data[offset:]), nil
 }
 
 func (f *File) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, ErrOutOfRange
+	}
 	f.fileData.Lock()
 	defer f.fileData.Unlock()
-	return f.read(b, off, io.EOF)
+	n, err = f.read(b, off)
+	if err == nil && n < len(b) {
+		err = io.EOF
+	}
+	return n, err
 }
 
 func (f *File) Truncate(size int64) error {
@@ -195,9 +232,16 @@ func (f *File) Truncate(size int64) error {
 		f.fileData.SetData(data[0:size])
 	}
 	f.fileData.SetModTime(time.Now())
+	f.changed()
 	return nil
 }
 
+func (f *File) changed() {
+	if f.notify != nil {
+		f.notify(vfs.EventWrite)
+	}
+}
+
 func (f *File) Seek(offset int64, whence int) (int64, error) {
 	if f.closed == true {
 		return 0, ErrFileClosed
@@ -206,13 +250,15 @@ func (f *File) Seek(offset int64, whence int) (int64, error) {
 	defer f.fileData.Unlock()
 	data := f.fileData.Data()
 	switch whence {
-	case 0:
-	case 1:
+	case io.SeekStart:
+	case io.SeekCurrent:
 		offset += f.offset
-	case 2:
+	case io.SeekEnd:
 		offset = int64(len(data)) + offset
+	default:
+		return 0, ErrOutOfRange
 	}
-	if offset < 0 || offset >= int64(len(data)) {
+	if offset < 0 {
 		return 0, ErrOutOfRange
 	}
 	f.offset = offset
@@ -238,13 +284,18 @@ func (f *File) write(buf []byte, offset int64) (int, error) {
 		return 0, ErrFileClosed
 	}
 	data := f.fileData.Data()
+	if gap := offset - int64(len(data)); gap > 0 {
+		data = append(data, make([]byte, gap)...)
+	}
 	n := int64(len(buf))
 	add := offset + n - int64(len(data))
 	copy(data[offset:], buf)
 	if add > 0 {
-		f.fileData.SetData(append(data, buf[n-add:]...))
+		data = append(data, buf[n-add:]...)
 	}
+	f.fileData.SetData(data)
 	f.fileData.SetModTime(time.Now())
+	f.changed()
 	return int(n), nil
 }
 
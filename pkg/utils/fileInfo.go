@@ -21,6 +21,8 @@ package utils
 import (
 	"os"
 	"time"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
 )
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -44,6 +46,21 @@ func (f *fileInfo) Name() string {
 	return f.name
 }
 
+type renamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+// NewRenamedFileInfo provides a file info for a file info
+// reporting a different name.
+func NewRenamedFileInfo(name string, fi os.FileInfo) os.FileInfo {
+	return &renamedFileInfo{fi, name}
+}
+
+func (f *renamedFileInfo) Name() string {
+	return f.name
+}
+
 func (f *fileInfo) Mode() os.FileMode {
 	f.fileData.Lock()
 	defer f.fileData.Unlock()
@@ -62,7 +79,56 @@ func (f *fileInfo) IsDir() bool {
 	return f.fileData.IsDir()
 }
 
-func (f *fileInfo) Sys() interface{} { return nil }
+// FileInfoSys is the system specific information provided
+// by FileInfo.Sys() for files of filesystems based on FileSystemSupport.
+type FileInfoSys struct {
+	node  FileData
+	uid   int
+	gid   int
+	nlink uint64
+}
+
+var _ vfs.OwnerInfo = (*FileInfoSys)(nil)
+var _ vfs.LinkInfo = (*FileInfoSys)(nil)
+var _ vfs.FileIdentity = (*FileInfoSys)(nil)
+
+// Uid returns the user id of the file or -1,
+// if the filesystem does not support ownership.
+func (s *FileInfoSys) Uid() int {
+	return s.uid
+}
+
+// Gid returns the group id of the file or -1,
+// if the filesystem does not support ownership.
+func (s *FileInfoSys) Gid() int {
+	return s.gid
+}
+
+// Nlink returns the number of directory entries
+// referring to the file.
+func (s *FileInfoSys) Nlink() uint64 {
+	return s.nlink
+}
+
+func (s *FileInfoSys) SameFile(sys interface{}) bool {
+	if o, ok := sys.(*FileInfoSys); ok {
+		return s.node == o.node
+	}
+	return false
+}
+
+func (f *fileInfo) Sys() interface{} {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	sys := &FileInfoSys{node: f.fileData, uid: -1, gid: -1, nlink: 1}
+	if o, ok := f.fileData.(FileDataOwnership); ok {
+		sys.uid, sys.gid = o.Owner()
+	}
+	if l, ok := f.fileData.(FileDataLinks); ok {
+		sys.nlink = uint64(l.Links())
+	}
+	return sys
+}
 
 func (f *fileInfo) Size() int64 {
 	f.fileData.Lock()
@@ -70,5 +136,8 @@ func (f *fileInfo) Size() int64 {
 	if f.fileData.IsDir() {
 		return int64(42)
 	}
+	if s, ok := f.fileData.(FileDataSize); ok {
+		return s.Size()
+	}
 	return int64(len(f.fileData.Data()))
 }
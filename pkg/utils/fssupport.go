@@ -36,9 +36,10 @@ type SupportAdapter interface {
 
 type FileSystemSupport struct {
 	FileSystemBase
-	name    string
-	root    FileData
-	adapter SupportAdapter
+	name     string
+	root     FileData
+	adapter  SupportAdapter
+	watchers Watchers
 }
 
 func NewFSSupport(name string, root FileData, adapter SupportAdapter) vfs.FileSystem {
@@ -49,6 +50,35 @@ func (m *FileSystemSupport) Name() string {
 	return m.name
 }
 
+var _ vfs.WatchableFileSystem = (*FileSystemSupport)(nil)
+
+// Watch reports the changes of a path done via this filesystem.
+func (m *FileSystemSupport) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	_, dn, f, n, err := m.createInfo(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	if f == nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: os.ErrNotExist}
+	}
+	return m.watchers.Watch(vfs.Join(m, dn, n), recursive), nil
+}
+
+// notify reports a change for an entry of a directory.
+func (m *FileSystemSupport) notify(dir, name string, op vfs.EventOp) {
+	m.watchers.Notify(vfs.Join(m, dir, name), op)
+}
+
+// newFileHandle provides a file handle reporting changes.
+func (m *FileSystemSupport) newFileHandle(name string, dir, n string, f FileData) *File {
+	h := newFileHandle(name, f)
+	path := vfs.Join(m, dir, n)
+	h.notify = func(op vfs.EventOp) {
+		m.watchers.Notify(path, op)
+	}
+	return h
+}
+
 func (m *FileSystemSupport) findFile(name string, link ...bool) (FileData, string, error) {
 	_, _, f, n, err := m.createInfo(name, link...)
 	if err != nil {
@@ -73,7 +103,7 @@ func (m *FileSystemSupport) createInfo(name string, link ...bool) (FileData, str
 }
 
 func (m *FileSystemSupport) Create(name string) (vfs.File, error) {
-	parent, _, f, n, err := m.createInfo(name)
+	parent, dn, f, n, err := m.createInfo(name)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +111,7 @@ func (m *FileSystemSupport) Create(name string) (vfs.File, error) {
 		if f.Mode()&fs.ModeType != 0 {
 			return nil, fs.ErrExist
 		}
-		h := newFileHandle(n, f)
+		h := m.newFileHandle(n, dn, n, f)
 		err := h.Truncate(0)
 		if err != nil {
 			return nil, err
@@ -96,11 +126,12 @@ func (m *FileSystemSupport) Create(name string) (vfs.File, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newFileHandle(n, f), nil
+	m.notify(dn, n, vfs.EventCreate)
+	return m.newFileHandle(n, dn, n, f), nil
 }
 
 func (m *FileSystemSupport) Mkdir(name string, perm os.FileMode) error {
-	parent, _, f, n, err := m.createInfo(name)
+	parent, dn, f, n, err := m.createInfo(name)
 	if err != nil {
 		return err
 	}
@@ -109,7 +140,11 @@ func (m *FileSystemSupport) Mkdir(name string, perm os.FileMode) error {
 	}
 	parent.Lock()
 	defer parent.Unlock()
-	return parent.Add(n, m.adapter.CreateDir(perm))
+	err = parent.Add(n, m.adapter.CreateDir(perm))
+	if err == nil {
+		m.notify(dn, n, vfs.EventCreate)
+	}
+	return err
 }
 
 func (m *FileSystemSupport) MkdirAll(path string, perm os.FileMode) error {
@@ -129,6 +164,7 @@ func (m *FileSystemSupport) MkdirAll(path string, perm os.FileMode) error {
 		if next == nil {
 			next = m.adapter.CreateDir(perm)
 			parent.Add(e, next.(FileData))
+			m.watchers.Notify(vfs.PathSeparatorString+strings.Join(elems[:i+1], vfs.PathSeparatorString), vfs.EventCreate)
 		}
 		parent.Unlock()
 		parent = next.(FileData)
@@ -137,15 +173,18 @@ func (m *FileSystemSupport) MkdirAll(path string, perm os.FileMode) error {
 }
 
 func (m *FileSystemSupport) Open(name string) (vfs.File, error) {
-	f, _, err := m.findFile(name)
+	_, dn, f, n, err := m.createInfo(name)
 	if err != nil {
 		return nil, err
 	}
-	return newFileHandle(name, f), nil
+	if f == nil {
+		return nil, os.ErrNotExist
+	}
+	return m.newFileHandle(name, dn, n, f), nil
 }
 
 func (m *FileSystemSupport) OpenFile(name string, flags int, perm os.FileMode) (vfs.File, error) {
-	dir, _, f, n, err := m.createInfo(name)
+	dir, dn, f, n, err := m.createInfo(name)
 	if err != nil {
 		return nil, err
 	}
@@ -166,6 +205,8 @@ func (m *FileSystemSupport) OpenFile(name string, flags int, perm os.FileMode) (
 				return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
 			}
 			f = a.(FileData)
+		} else {
+			m.notify(dn, n, vfs.EventCreate)
 		}
 		dir.Unlock()
 	} else {
@@ -173,7 +214,7 @@ func (m *FileSystemSupport) OpenFile(name string, flags int, perm os.FileMode) (
 			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
 		}
 	}
-	h := newFileHandle(name, f)
+	h := m.newFileHandle(name, dn, n, f)
 
 	if flags&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) == os.O_RDONLY {
 		h.readOnly = true
@@ -193,7 +234,7 @@ func (m *FileSystemSupport) OpenFile(name string, flags int, perm os.FileMode) (
 }
 
 func (m *FileSystemSupport) Remove(name string) error {
-	dir, _, f, n, err := m.createInfo(name, false)
+	dir, dn, f, n, err := m.createInfo(name, false)
 	if err != nil {
 		return err
 	}
@@ -213,11 +254,15 @@ func (m *FileSystemSupport) Remove(name string) error {
 	}
 	dir.Lock()
 	defer dir.Unlock()
-	return dir.Del(n)
+	err = dir.Del(n)
+	if err == nil {
+		m.notify(dn, n, vfs.EventRemove)
+	}
+	return err
 }
 
 func (m *FileSystemSupport) RemoveAll(name string) error {
-	dir, _, _, n, err := m.createInfo(name, false)
+	dir, dn, _, n, err := m.createInfo(name, false)
 	if err != nil {
 		return err
 	}
@@ -226,18 +271,22 @@ func (m *FileSystemSupport) RemoveAll(name string) error {
 	}
 	dir.Lock()
 	defer dir.Unlock()
-	return dir.Del(n)
+	err = dir.Del(n)
+	if err == nil {
+		m.notify(dn, n, vfs.EventRemove)
+	}
+	return err
 }
 
 func (m *FileSystemSupport) Rename(oldname, newname string) error {
-	odir, _, fo, o, err := m.createInfo(oldname, false)
+	odir, odn, fo, o, err := m.createInfo(oldname, false)
 	if err != nil {
 		return err
 	}
 	if o == "" {
 		return errors.New("cannot rename root dir")
 	}
-	ndir, _, fn, n, err := m.createInfo(newname)
+	ndir, ndn, fn, n, err := m.createInfo(newname, false)
 	if err != nil {
 		return err
 	}
@@ -245,16 +294,29 @@ func (m *FileSystemSupport) Rename(oldname, newname string) error {
 		return os.ErrNotExist
 	}
 	if fn != nil {
-		return os.ErrExist
+		if fn == fo {
+			return nil
+		}
+		// like rename(2), an existing non-directory is replaced.
+		if fo.Mode().IsDir() || fn.Mode().IsDir() {
+			return os.ErrExist
+		}
 	}
 
 	ndir.Lock()
-	err = ndir.Add(n, fo)
+	if fn != nil {
+		err = ndir.Del(n)
+	}
+	if err == nil {
+		err = ndir.Add(n, fo)
+	}
 	ndir.Unlock()
 	if err == nil {
 		odir.Lock()
 		odir.Del(o)
 		odir.Unlock()
+		m.notify(odn, o, vfs.EventRename)
+		m.notify(ndn, n, vfs.EventCreate)
 	}
 	return err
 }
@@ -275,39 +337,134 @@ func (m *FileSystemSupport) Stat(name string) (os.FileInfo, error) {
 	if f == nil {
 		return nil, os.ErrNotExist
 	}
+	// like os.Stat, report the name of a final symbolic link
+	if _, ln, err := m.findFile(name, false); err == nil {
+		n = ln
+	}
 	return NewFileInfo(n, f), nil
 }
 
+// findEntry finds a file and provides its canonical path.
+func (m *FileSystemSupport) findEntry(name string, link ...bool) (FileData, string, error) {
+	_, dn, f, n, err := m.createInfo(name, link...)
+	if err != nil {
+		return nil, "", err
+	}
+	if f == nil {
+		return nil, "", os.ErrNotExist
+	}
+	return f, vfs.Join(m, dn, n), nil
+}
+
 func (m *FileSystemSupport) Chmod(name string, mode os.FileMode) error {
-	f, _, err := m.findFile(name)
+	f, path, err := m.findEntry(name)
 	if err != nil {
 		return err
 	}
 	f.Lock()
 	defer f.Unlock()
 	f.SetMode((f.Mode() & (^os.ModePerm)) | (mode & os.ModePerm))
+	m.watchers.Notify(path, vfs.EventChmod)
 	return nil
 }
 
 func (m *FileSystemSupport) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	f, _, err := m.findFile(name)
+	f, path, err := m.findEntry(name)
 	if err != nil {
 		return err
 	}
 	f.Lock()
 	defer f.Unlock()
 	f.SetModTime(mtime)
+	m.watchers.Notify(path, vfs.EventChmod)
+	return nil
+}
+
+func (m *FileSystemSupport) Chown(name string, uid, gid int) error {
+	f, path, err := m.findEntry(name)
+	if err != nil {
+		return err
+	}
+	err = chown(f, "chown", name, uid, gid)
+	if err == nil {
+		m.watchers.Notify(path, vfs.EventChmod)
+	}
+	return err
+}
+
+func (m *FileSystemSupport) Lchown(name string, uid, gid int) error {
+	f, path, err := m.findEntry(name, false)
+	if err != nil {
+		return err
+	}
+	err = chown(f, "lchown", name, uid, gid)
+	if err == nil {
+		m.watchers.Notify(path, vfs.EventChmod)
+	}
+	return err
+}
+
+func chown(f FileData, op, name string, uid, gid int) error {
+	o, ok := f.(FileDataOwnership)
+	if !ok {
+		return &os.PathError{Op: op, Path: name, Err: vfs.ErrNotSupported}
+	}
+	f.Lock()
+	defer f.Unlock()
+	ouid, ogid := o.Owner()
+	if uid == -1 {
+		uid = ouid
+	}
+	if gid == -1 {
+		gid = ogid
+	}
+	o.SetOwner(uid, gid)
 	return nil
 }
 
 func (m *FileSystemSupport) Symlink(oldname, newname string) error {
-	parent, _, _, n, err := m.createInfo(newname)
+	parent, dn, _, n, err := m.createInfo(newname)
 	if err != nil {
 		return err
 	}
 	parent.Lock()
 	defer parent.Unlock()
-	return parent.Add(n, m.adapter.CreateSymlink(oldname, os.ModePerm))
+	err = parent.Add(n, m.adapter.CreateSymlink(oldname, os.ModePerm))
+	if err == nil {
+		m.notify(dn, n, vfs.EventCreate)
+	}
+	return err
+}
+
+func (m *FileSystemSupport) Link(oldname, newname string) error {
+	f, _, err := m.findFile(oldname, false)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	if _, ok := f.(FileDataLinks); !ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: vfs.ErrNotSupported}
+	}
+	f.Lock()
+	dir := f.IsDir()
+	f.Unlock()
+	if dir {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrPermission}
+	}
+	parent, dn, fn, n, err := m.createInfo(newname)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	if fn != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	parent.Lock()
+	defer parent.Unlock()
+	err = parent.Add(n, f)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	m.notify(dn, n, vfs.EventCreate)
+	return nil
 }
 
 func (m *FileSystemSupport) Readlink(name string) (string, error) {
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package utils
+
+import (
+	"math"
+	"os"
+	"sync"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// fileLock describes an advisory lock held by a file handle.
+// Locks for the complete file (flock-like) and byte range locks
+// (fcntl-like) are independent of each other, like on Linux.
+type fileLock struct {
+	owner *File
+	typ   vfs.LockType
+	whole bool
+	start int64
+	end   int64
+}
+
+func (l *fileLock) conflicts(o *fileLock) bool {
+	if l.owner == o.owner || l.whole != o.whole {
+		return false
+	}
+	if l.typ != vfs.LockExclusive && o.typ != vfs.LockExclusive {
+		return false
+	}
+	return l.whole || (l.start < o.end && o.start < l.end)
+}
+
+// lockTable keeps track of the advisory locks held for
+// the FileData objects of all in-memory filesystems.
+type lockTable struct {
+	lock  sync.Mutex
+	cond  *sync.Cond
+	locks map[FileData][]*fileLock
+}
+
+var locks = newLockTable()
+
+func newLockTable() *lockTable {
+	t := &lockTable{locks: map[FileData][]*fileLock{}}
+	t.cond = sync.NewCond(&t.lock)
+	return t
+}
+
+func (t *lockTable) acquire(data FileData, l *fileLock, wait bool) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for t.conflicts(data, l) {
+		if !wait {
+			return vfs.ErrWouldBlock
+		}
+		t.cond.Wait()
+	}
+	t.remove(data, l)
+	t.locks[data] = append(t.locks[data], l)
+	return nil
+}
+
+func (t *lockTable) release(data FileData, l *fileLock) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.remove(data, l)
+	t.cond.Broadcast()
+}
+
+// releaseAll releases all locks held by the given file handle.
+func (t *lockTable) releaseAll(data FileData, owner *File) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	list := t.locks[data]
+	if len(list) == 0 {
+		return
+	}
+	var n []*fileLock
+	for _, e := range list {
+		if e.owner != owner {
+			n = append(n, e)
+		}
+	}
+	t.set(data, n)
+	t.cond.Broadcast()
+}
+
+func (t *lockTable) conflicts(data FileData, l *fileLock) bool {
+	for _, e := range t.locks[data] {
+		if e.conflicts(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove removes the area described by the given lock from
+// the locks held by its owner. Range locks partially
+// covered are split.
+func (t *lockTable) remove(data FileData, l *fileLock) {
+	var n []*fileLock
+	for _, e := range t.locks[data] {
+		if e.owner != l.owner || e.whole != l.whole {
+			n = append(n, e)
+			continue
+		}
+		if l.whole {
+			continue
+		}
+		if e.end <= l.start || l.end <= e.start {
+			n = append(n, e)
+			continue
+		}
+		if e.start < l.start {
+			n = append(n, &fileLock{owner: e.owner, typ: e.typ, start: e.start, end: l.start})
+		}
+		if l.end < e.end {
+			n = append(n, &fileLock{owner: e.owner, typ: e.typ, start: l.end, end: e.end})
+		}
+	}
+	t.set(data, n)
+}
+
+func (t *lockTable) set(data FileData, list []*fileLock) {
+	if len(list) == 0 {
+		delete(t.locks, data)
+	} else {
+		t.locks[data] = list
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+var _ vfs.RangeLockableFile = (*File)(nil)
+
+func (f *File) checkClosed(op string) error {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return &os.PathError{Op: op, Path: f.name, Err: ErrFileClosed}
+	}
+	return nil
+}
+
+func (f *File) Lock(t vfs.LockType, wait bool) error {
+	if err := f.checkClosed("lock"); err != nil {
+		return err
+	}
+	err := locks.acquire(f.fileData, &fileLock{owner: f, typ: t, whole: true}, wait)
+	if err != nil {
+		return &os.PathError{Op: "lock", Path: f.name, Err: err}
+	}
+	return nil
+}
+
+func (f *File) Unlock() error {
+	if err := f.checkClosed("unlock"); err != nil {
+		return err
+	}
+	locks.release(f.fileData, &fileLock{owner: f, whole: true})
+	return nil
+}
+
+func (f *File) LockRange(offset, length int64, t vfs.LockType, wait bool) error {
+	l, err := f.rangeLock("lock", offset, length)
+	if err != nil {
+		return err
+	}
+	l.typ = t
+	err = locks.acquire(f.fileData, l, wait)
+	if err != nil {
+		return &os.PathError{Op: "lock", Path: f.name, Err: err}
+	}
+	return nil
+}
+
+func (f *File) UnlockRange(offset, length int64) error {
+	l, err := f.rangeLock("unlock", offset, length)
+	if err != nil {
+		return err
+	}
+	locks.release(f.fileData, l)
+	return nil
+}
+
+func (f *File) rangeLock(op string, offset, length int64) (*fileLock, error) {
+	if err := f.checkClosed(op); err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 {
+		return nil, &os.PathError{Op: op, Path: f.name, Err: os.ErrInvalid}
+	}
+	end := int64(math.MaxInt64)
+	if length > 0 && length <= math.MaxInt64-offset {
+		end = offset + length
+	}
+	return &fileLock{owner: f, start: offset, end: end}, nil
+}
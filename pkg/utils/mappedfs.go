@@ -21,8 +21,10 @@ package utils
 import (
 	"errors"
 	"fmt"
+	iofs "io/fs"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mandelsoft/vfs/pkg/vfs"
@@ -32,10 +34,29 @@ type PathMapper interface {
 	MapPath(path string) (vfs.FileSystem, string)
 }
 
+// FileTracker is an optional interface for a PathMapper
+// to keep track of the files opened for mapped paths.
+type FileTracker interface {
+	// Opened is called for a file opened for the given
+	// (evaluated) path. The returned function, if not nil,
+	// is called when the file is closed.
+	Opened(path string) func()
+}
+
+// LinkValidator is an optional interface for a PathMapper
+// to restrict the evaluation of symbolic links.
+type LinkValidator interface {
+	// ValidateLink is called for a symbolic link found at the
+	// given (evaluated) path before its (cleaned) target is
+	// evaluated.
+	ValidateLink(path, target string) error
+}
+
 type MappedFileSystem struct {
 	FileSystemBase
-	mapper PathMapper
-	base   vfs.FileSystem
+	mapper      PathMapper
+	base        vfs.FileSystem
+	crossRename bool
 }
 
 func NewMappedFileSystem(root vfs.FileSystem, mapper PathMapper) *MappedFileSystem {
@@ -49,6 +70,14 @@ func (m *MappedFileSystem) Cleanup() error {
 	return nil
 }
 
+// EnableCrossRename enables renames across the mapped filesystems.
+// Such a rename is executed by copying the entry to the target
+// filesystem and deleting it afterwards. Otherwise, those renames
+// fail with vfs.ErrCrossDevice.
+func (m *MappedFileSystem) EnableCrossRename(enable bool) {
+	m.crossRename = enable
+}
+
 func (m *MappedFileSystem) Base() vfs.FileSystem {
 	return m.base
 }
@@ -126,6 +155,16 @@ func (m *MappedFileSystem) mapPath(path string, link ...bool) (vfs.FileSystem, s
 				if vol != "" {
 					return nil, "", "", fmt.Errorf("volume links not possible: %s: %s", l, vol+newpath)
 				}
+				if v, ok := m.mapper.(LinkValidator); ok {
+					target := newpath
+					if !isAbs(target) {
+						target = vfs.Join(m.base, r, target)
+					}
+					err = v.ValidateLink(vfs.Join(m.base, r, b), vfs.Clean(m.base, target))
+					if err != nil {
+						return nil, "", "", err
+					}
+				}
 				if isAbs(newpath) {
 					r = "/"
 				}
@@ -159,27 +198,183 @@ func (m *MappedFileSystem) Chmod(name string, mode os.FileMode) (err error) {
 	return fs.Chmod(l, mode)
 }
 
-func (m *MappedFileSystem) Stat(name string) (fi os.FileInfo, err error) {
+func (m *MappedFileSystem) Chown(name string, uid, gid int) (err error) {
 	fs, l, _, err := m.mapPath(name)
+	if err != nil {
+		return &os.PathError{Op: "chown", Path: name, Err: err}
+	}
+	return vfs.Chown(fs, l, uid, gid)
+}
+
+func (m *MappedFileSystem) Lchown(name string, uid, gid int) (err error) {
+	fs, l, _, err := m.mapPath(name, false)
+	if err != nil {
+		return &os.PathError{Op: "lchown", Path: name, Err: err}
+	}
+	return vfs.Lchown(fs, l, uid, gid)
+}
+
+// fileInfo reports a file info of a mapped filesystem
+// with the name of the mapped path. This is required
+// for files mapped to the root of a filesystem, like mount points.
+func (m *MappedFileSystem) fileInfo(path string, fi os.FileInfo) os.FileInfo {
+	if path == vfs.PathSeparatorString {
+		return fi
+	}
+	if b := vfs.Base(m.base, path); fi.Name() != b {
+		return NewRenamedFileInfo(b, fi)
+	}
+	return fi
+}
+
+func (m *MappedFileSystem) Stat(name string) (fi os.FileInfo, err error) {
+	fs, l, r, err := m.mapPath(name)
 	if err != nil {
 		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
 	}
-	return fs.Stat(l)
+	fi, err = fs.Stat(l)
+	if err != nil {
+		return nil, err
+	}
+	return m.fileInfo(r, fi), nil
 }
 
 func (m *MappedFileSystem) Rename(oldname, newname string) (err error) {
-	oldfs, o, _, err := m.mapPath(oldname, false)
+	oldfs, o, oldr, err := m.mapPath(oldname, false)
 	if err != nil {
 		return &os.PathError{Op: "rename", Path: oldname, Err: err}
 	}
-	newfs, n, _, err := m.mapPath(newname)
+	newfs, n, newr, err := m.mapPath(newname)
 	if err != nil {
 		return &os.PathError{Op: "rename", Path: newname, Err: err}
 	}
 	if oldfs == newfs {
 		return oldfs.Rename(o, n)
 	}
-	return fmt.Errorf("no cross filesystem rename operation possible: %s -> %s", oldname, newname)
+	if !m.crossRename {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: vfs.ErrCrossDevice}
+	}
+	if strings.HasPrefix(newr, oldr+vfs.PathSeparatorString) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: iofs.ErrInvalid}
+	}
+	err = crossRename(oldfs, o, newfs, n)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// crossRename moves an entry to another filesystem by copying
+// it to a staging directory in the target filesystem, renaming
+// it to its final name and deleting the source afterwards.
+// On failure, it tries to restore the original state.
+func crossRename(oldfs vfs.FileSystem, o string, newfs vfs.FileSystem, n string) error {
+	fi, err := oldfs.Lstat(o)
+	if err != nil {
+		return err
+	}
+	staging, err := vfs.TempDir(newfs, vfs.Dir(newfs, n), ".rename-")
+	if err != nil {
+		return err
+	}
+	defer newfs.RemoveAll(staging)
+
+	tmp := vfs.Join(newfs, staging, "entry")
+	err = copyEntry(oldfs, o, fi, newfs, tmp)
+	if err != nil {
+		return err
+	}
+	err = newfs.Rename(tmp, n)
+	if err != nil {
+		return err
+	}
+	err = oldfs.RemoveAll(o)
+	if err != nil {
+		// restore already deleted parts of the source
+		if rerr := restoreEntry(newfs, n, oldfs, o); rerr == nil {
+			newfs.RemoveAll(n)
+		}
+		return err
+	}
+	return nil
+}
+
+// copyEntry copies a file, symbolic link or directory tree preserving
+// modes, times, extended attributes and ownership (if possible).
+func copyEntry(srcfs vfs.FileSystem, src string, fi os.FileInfo, dstfs vfs.FileSystem, dst string) error {
+	switch {
+	case fi.IsDir():
+		return vfs.CopyDir(srcfs, src, dstfs, dst, vfs.CopyOwnership, vfs.CopyTimes)
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := srcfs.Readlink(src)
+		if err != nil {
+			return err
+		}
+		err = dstfs.Symlink(link, dst)
+		if err != nil {
+			return err
+		}
+		err = vfs.CopyXattrs(srcfs, src, dstfs, dst)
+		if err != nil && !vfs.IsErrPermission(err) && !vfs.IsErrNotSupported(err) {
+			return err
+		}
+		if uid, gid, ok := vfs.Owner(fi); ok {
+			err = vfs.Lchown(dstfs, dst, uid, gid)
+			if err != nil && !vfs.IsErrNotSupported(err) {
+				return err
+			}
+		}
+		return nil
+	case fi.Mode().IsRegular():
+		return vfs.CopyFile(srcfs, src, dstfs, dst, vfs.CopyOwnership, vfs.CopyTimes)
+	default:
+		return fmt.Errorf("%s: file type not supported", src)
+	}
+}
+
+// restoreEntry copies back all entries of a moved
+// directory tree, which are missing in the source.
+func restoreEntry(fs vfs.FileSystem, path string, srcfs vfs.FileSystem, src string) error {
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		return err
+	}
+	si, err := srcfs.Lstat(src)
+	if err != nil {
+		if !vfs.IsErrNotExist(err) {
+			return err
+		}
+		return copyEntry(fs, path, fi, srcfs, src)
+	}
+	if !fi.IsDir() || !si.IsDir() {
+		return nil
+	}
+	entries, err := vfs.ReadDir(fs, path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err = restoreEntry(fs, vfs.Join(fs, path, e.Name()), srcfs, vfs.Join(srcfs, src, e.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MappedFileSystem) Link(oldname, newname string) (err error) {
+	oldfs, o, _, err := m.mapPath(oldname, false)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	newfs, n, _, err := m.mapPath(newname)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	if oldfs != newfs {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: vfs.ErrCrossDevice}
+	}
+	return vfs.Link(oldfs, o, n)
 }
 
 func (m *MappedFileSystem) RemoveAll(name string) (err error) {
@@ -207,7 +402,7 @@ func (m *MappedFileSystem) OpenFile(name string, flag int, mode os.FileMode) (f
 	if err != nil {
 		return nil, err
 	}
-	return NewRenamedFile(n, sourcef), nil
+	return m.newFile(sourcef, n, fs), nil
 }
 
 func (m *MappedFileSystem) Open(name string) (f vfs.File, err error) {
@@ -219,7 +414,7 @@ func (m *MappedFileSystem) Open(name string) (f vfs.File, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewRenamedFile(n, sourcef), nil
+	return m.newFile(sourcef, n, fs), nil
 }
 
 func (m *MappedFileSystem) Mkdir(name string, mode os.FileMode) (err error) {
@@ -269,15 +464,63 @@ func (m *MappedFileSystem) Create(name string) (f vfs.File, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewRenamedFile(n, sourcef), nil
+	return m.newFile(sourcef, n, fs), nil
 }
 
 func (m *MappedFileSystem) Lstat(name string) (os.FileInfo, error) {
-	fs, l, _, err := m.mapPath(name, false)
+	fs, l, r, err := m.mapPath(name, false)
 	if err != nil {
 		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
 	}
-	return fs.Lstat(l)
+	fi, err := fs.Lstat(l)
+	if err != nil {
+		return nil, err
+	}
+	return m.fileInfo(r, fi), nil
+}
+
+// Watch watches a path of the filesystem it is mapped to.
+// Events are reported with the paths of the mapped filesystem.
+// Events for entries mapped to other filesystems are dropped.
+func (m *MappedFileSystem) Watch(name string, recursive bool) (vfs.Watcher, error) {
+	fs, l, r, err := m.mapPath(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	return m.WatchMapped(fs, l, r, recursive)
+}
+
+// WatchMapped watches a path l of a filesystem mapped to path r.
+func (m *MappedFileSystem) WatchMapped(fs vfs.FileSystem, l, r string, recursive bool) (vfs.Watcher, error) {
+	w, err := vfs.Watch(fs, l, recursive)
+	if err != nil {
+		return nil, err
+	}
+	mapping := func(e vfs.Event) (vfs.Event, bool) {
+		rel, ok := relPath(l, e.Path)
+		if !ok {
+			return e, false
+		}
+		e.Path = vfs.Join(m.base, r, rel)
+		mfs, _ := m.mapper.MapPath(e.Path)
+		return e, mfs == fs
+	}
+	return NewMappedWatcher(mapping, w), nil
+}
+
+// relPath provides the path relative to a base path.
+func relPath(base, path string) (string, bool) {
+	if path == base {
+		return "", true
+	}
+	prefix := base
+	if !strings.HasSuffix(prefix, vfs.PathSeparatorString) {
+		prefix += vfs.PathSeparatorString
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	return path[len(prefix):], true
 }
 
 func (m *MappedFileSystem) Symlink(oldname, newname string) error {
@@ -295,3 +538,76 @@ func (m *MappedFileSystem) Readlink(name string) (string, error) {
 	}
 	return fs.Readlink(l)
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// mappedFile is a file of a mapped filesystem.
+// Directory entries mapped to another filesystem (like mount points)
+// are reported with the file info of the mapped filesystem.
+type mappedFile struct {
+	RenamedFile
+	fs     *MappedFileSystem
+	dirfs  vfs.FileSystem
+	lock   sync.Mutex
+	closed func()
+}
+
+func (m *MappedFileSystem) newFile(file vfs.File, path string, fs vfs.FileSystem) vfs.File {
+	f := &mappedFile{RenamedFile: RenamedFile{file, path}, fs: m, dirfs: fs}
+	if t, ok := m.mapper.(FileTracker); ok {
+		f.closed = t.Opened(path)
+	}
+	return f
+}
+
+func (f *mappedFile) Close() error {
+	err := f.File.Close()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed != nil {
+		f.closed()
+		f.closed = nil
+	}
+	return err
+}
+
+func (f *mappedFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return f.fs.fileInfo(f.name, fi), nil
+}
+
+func (f *mappedFile) mapInfo(fi os.FileInfo) os.FileInfo {
+	fs, l := f.fs.mapper.MapPath(vfs.Join(f.fs.base, f.name, fi.Name()))
+	if fs == f.dirfs {
+		return fi
+	}
+	mfi, err := fs.Lstat(l)
+	if err != nil {
+		return fi
+	}
+	return NewRenamedFileInfo(fi.Name(), mfi)
+}
+
+func (f *mappedFile) Readdir(count int) ([]os.FileInfo, error) {
+	list, err := f.File.Readdir(count)
+	for i, fi := range list {
+		list[i] = f.mapInfo(fi)
+	}
+	return list, err
+}
+
+func (f *mappedFile) ReadDir(count int) ([]vfs.DirEntry, error) {
+	list, err := f.File.ReadDir(count)
+	for i, e := range list {
+		fi, ierr := e.Info()
+		if ierr == nil {
+			if mfi := f.mapInfo(fi); mfi != fi {
+				list[i] = iofs.FileInfoToDirEntry(mfi)
+			}
+		}
+	}
+	return list, err
+}
@@ -42,3 +42,19 @@ func (r *RenamedFile) Name() string {
 func (r *RenamedFile) OSFile() *os.File {
 	return OSFile(r.File)
 }
+
+func (r *RenamedFile) Lock(t vfs.LockType, wait bool) error {
+	return vfs.Lock(r.File, t, wait)
+}
+
+func (r *RenamedFile) Unlock() error {
+	return vfs.Unlock(r.File)
+}
+
+func (r *RenamedFile) LockRange(offset, length int64, t vfs.LockType, wait bool) error {
+	return vfs.LockRange(r.File, offset, length, t, wait)
+}
+
+func (r *RenamedFile) UnlockRange(offset, length int64) error {
+	return vfs.UnlockRange(r.File, offset, length)
+}
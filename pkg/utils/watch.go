@@ -0,0 +1,196 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// Watched checks whether a change of path is reported
+// by a watch for the given path.
+func Watched(watch string, recursive bool, path string) bool {
+	if path == watch {
+		return true
+	}
+	prefix := watch
+	if !strings.HasSuffix(prefix, vfs.PathSeparatorString) {
+		prefix += vfs.PathSeparatorString
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return recursive || !strings.Contains(path[len(prefix):], vfs.PathSeparatorString)
+}
+
+// Watcher is a vfs.Watcher queuing the events sent to it,
+// so that sending never blocks.
+type Watcher struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	queue   []vfs.Event
+	closed  bool
+	events  chan vfs.Event
+	done    chan struct{}
+	cleanup func() error
+}
+
+var _ vfs.Watcher = (*Watcher)(nil)
+
+// NewWatcher provides a new Watcher. The optional cleanup function
+// is called when the watcher is closed.
+func NewWatcher(cleanup func() error) *Watcher {
+	w := &Watcher{
+		events:  make(chan vfs.Event),
+		done:    make(chan struct{}),
+		cleanup: cleanup,
+	}
+	w.cond = sync.NewCond(&w.lock)
+	go w.deliver()
+	return w
+}
+
+func (w *Watcher) deliver() {
+	defer close(w.events)
+	for {
+		w.lock.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.closed {
+			w.lock.Unlock()
+			return
+		}
+		e := w.queue[0]
+		w.queue = w.queue[1:]
+		w.lock.Unlock()
+
+		select {
+		case w.events <- e:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Send queues an event.
+func (w *Watcher) Send(e vfs.Event) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.closed {
+		w.queue = append(w.queue, e)
+		w.cond.Signal()
+	}
+}
+
+func (w *Watcher) Events() <-chan vfs.Event {
+	return w.events
+}
+
+func (w *Watcher) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	w.cond.Signal()
+	w.lock.Unlock()
+	if w.cleanup != nil {
+		return w.cleanup()
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Watchers is a registry for watches of a filesystem
+// dispatching change notifications to matching watches.
+type Watchers struct {
+	lock    sync.Mutex
+	watches map[*Watcher]watch
+}
+
+type watch struct {
+	path      string
+	recursive bool
+}
+
+// Watch registers a watch for a canonical path.
+func (r *Watchers) Watch(path string, recursive bool) vfs.Watcher {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var w *Watcher
+	w = NewWatcher(func() error {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.watches, w)
+		return nil
+	})
+	if r.watches == nil {
+		r.watches = map[*Watcher]watch{}
+	}
+	r.watches[w] = watch{path, recursive}
+	return w
+}
+
+// Notify reports a change of the given canonical path.
+func (r *Watchers) Notify(path string, op vfs.EventOp) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for w, e := range r.watches {
+		if Watched(e.path, e.recursive, path) {
+			w.Send(vfs.Event{Path: path, Op: op})
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewMappedWatcher provides a watcher forwarding the events of
+// other watchers. The mapping function maps the events, they
+// are dropped, if it returns false.
+func NewMappedWatcher(mapping func(e vfs.Event) (vfs.Event, bool), watchers ...vfs.Watcher) vfs.Watcher {
+	w := NewWatcher(func() error {
+		var err error
+		for _, s := range watchers {
+			if cerr := s.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+		return err
+	})
+	for _, s := range watchers {
+		go func(s vfs.Watcher) {
+			for e := range s.Events() {
+				if e, ok := mapping(e); ok {
+					w.Send(e)
+				}
+			}
+		}(s)
+	}
+	return w
+}
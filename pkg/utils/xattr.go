@@ -0,0 +1,199 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package utils
+
+import (
+	"os"
+	"sort"
+
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+func (m *FileSystemSupport) xattrData(op, name string, link bool) (FileDataXattr, string, error) {
+	f, path, err := m.findEntry(name, link)
+	if err != nil {
+		return nil, "", &os.PathError{Op: op, Path: name, Err: err}
+	}
+	x, ok := f.(FileDataXattr)
+	if !ok {
+		return nil, "", &os.PathError{Op: op, Path: name, Err: vfs.ErrNotSupported}
+	}
+	return x, path, nil
+}
+
+func (m *FileSystemSupport) getxattr(op, name, attr string, link bool) ([]byte, error) {
+	x, _, err := m.xattrData(op, name, link)
+	if err != nil {
+		return nil, err
+	}
+	f := x.(FileData)
+	f.Lock()
+	defer f.Unlock()
+	data, ok := x.GetXattr(attr)
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: vfs.ErrNoAttr}
+	}
+	return append([]byte{}, data...), nil
+}
+
+func (m *FileSystemSupport) setxattr(op, name, attr string, data []byte, flags int, link bool) error {
+	x, path, err := m.xattrData(op, name, link)
+	if err != nil {
+		return err
+	}
+	f := x.(FileData)
+	f.Lock()
+	defer f.Unlock()
+	_, ok := x.GetXattr(attr)
+	if ok && flags&vfs.XATTR_CREATE != 0 {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrExist}
+	}
+	if !ok && flags&vfs.XATTR_REPLACE != 0 {
+		return &os.PathError{Op: op, Path: name, Err: vfs.ErrNoAttr}
+	}
+	x.SetXattr(attr, append([]byte{}, data...))
+	m.watchers.Notify(path, vfs.EventChmod)
+	return nil
+}
+
+func (m *FileSystemSupport) listxattr(op, name string, link bool) ([]string, error) {
+	x, _, err := m.xattrData(op, name, link)
+	if err != nil {
+		return nil, err
+	}
+	f := x.(FileData)
+	f.Lock()
+	defer f.Unlock()
+	list := x.ListXattr()
+	sort.Strings(list)
+	return list, nil
+}
+
+func (m *FileSystemSupport) removexattr(op, name, attr string, link bool) error {
+	x, path, err := m.xattrData(op, name, link)
+	if err != nil {
+		return err
+	}
+	f := x.(FileData)
+	f.Lock()
+	defer f.Unlock()
+	if !x.RemoveXattr(attr) {
+		return &os.PathError{Op: op, Path: name, Err: vfs.ErrNoAttr}
+	}
+	m.watchers.Notify(path, vfs.EventChmod)
+	return nil
+}
+
+func (m *FileSystemSupport) Getxattr(name, attr string) ([]byte, error) {
+	return m.getxattr("getxattr", name, attr, true)
+}
+
+func (m *FileSystemSupport) Setxattr(name, attr string, data []byte, flags int) error {
+	return m.setxattr("setxattr", name, attr, data, flags, true)
+}
+
+func (m *FileSystemSupport) Listxattr(name string) ([]string, error) {
+	return m.listxattr("listxattr", name, true)
+}
+
+func (m *FileSystemSupport) Removexattr(name, attr string) error {
+	return m.removexattr("removexattr", name, attr, true)
+}
+
+func (m *FileSystemSupport) Lgetxattr(name, attr string) ([]byte, error) {
+	return m.getxattr("lgetxattr", name, attr, false)
+}
+
+func (m *FileSystemSupport) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return m.setxattr("lsetxattr", name, attr, data, flags, false)
+}
+
+func (m *FileSystemSupport) Llistxattr(name string) ([]string, error) {
+	return m.listxattr("llistxattr", name, false)
+}
+
+func (m *FileSystemSupport) Lremovexattr(name, attr string) error {
+	return m.removexattr("lremovexattr", name, attr, false)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (m *MappedFileSystem) Getxattr(name, attr string) ([]byte, error) {
+	fs, l, _, err := m.mapPath(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: name, Err: err}
+	}
+	return vfs.Getxattr(fs, l, attr)
+}
+
+func (m *MappedFileSystem) Setxattr(name, attr string, data []byte, flags int) error {
+	fs, l, _, err := m.mapPath(name)
+	if err != nil {
+		return &os.PathError{Op: "setxattr", Path: name, Err: err}
+	}
+	return vfs.Setxattr(fs, l, attr, data, flags)
+}
+
+func (m *MappedFileSystem) Listxattr(name string) ([]string, error) {
+	fs, l, _, err := m.mapPath(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "listxattr", Path: name, Err: err}
+	}
+	return vfs.Listxattr(fs, l)
+}
+
+func (m *MappedFileSystem) Removexattr(name, attr string) error {
+	fs, l, _, err := m.mapPath(name)
+	if err != nil {
+		return &os.PathError{Op: "removexattr", Path: name, Err: err}
+	}
+	return vfs.Removexattr(fs, l, attr)
+}
+
+func (m *MappedFileSystem) Lgetxattr(name, attr string) ([]byte, error) {
+	fs, l, _, err := m.mapPath(name, false)
+	if err != nil {
+		return nil, &os.PathError{Op: "lgetxattr", Path: name, Err: err}
+	}
+	return vfs.Lgetxattr(fs, l, attr)
+}
+
+func (m *MappedFileSystem) Lsetxattr(name, attr string, data []byte, flags int) error {
+	fs, l, _, err := m.mapPath(name, false)
+	if err != nil {
+		return &os.PathError{Op: "lsetxattr", Path: name, Err: err}
+	}
+	return vfs.Lsetxattr(fs, l, attr, data, flags)
+}
+
+func (m *MappedFileSystem) Llistxattr(name string) ([]string, error) {
+	fs, l, _, err := m.mapPath(name, false)
+	if err != nil {
+		return nil, &os.PathError{Op: "llistxattr", Path: name, Err: err}
+	}
+	return vfs.Llistxattr(fs, l)
+}
+
+func (m *MappedFileSystem) Lremovexattr(name, attr string) error {
+	fs, l, _, err := m.mapPath(name, false)
+	if err != nil {
+		return &os.PathError{Op: "lremovexattr", Path: name, Err: err}
+	}
+	return vfs.Lremovexattr(fs, l, attr)
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// AtomicWriter writes the content of a file to a temporary file
+// in the directory of the target file. When it is closed, the
+// temporary file replaces the target file by a rename operation,
+// so readers either see the old or the complete new content.
+// Symbolic links are followed, the mode and ownership (if permitted)
+// of an already existing target file are preserved.
+//
+// If the temporary file and the target file are located
+// on different filesystems (for example on different mounts),
+// Close fails with an error matching IsErrCrossDevice.
+type AtomicWriter struct {
+	fs     FileSystem
+	name   string
+	file   File
+	tmp    string
+	target FileInfo
+	done   bool
+}
+
+var _ io.WriteCloser = (*AtomicWriter)(nil)
+
+// NewAtomicWriter creates a writer atomically replacing the
+// content of the given file when it is closed. If the file does not
+// exist it is created with the given permissions.
+// Abort discards the written content, it can be deferred,
+// because it does nothing after a successful Close.
+func NewAtomicWriter(fs FileSystem, name string, perm FileMode) (*AtomicWriter, error) {
+	path, err := Canonical(fs, name, false)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fs.Stat(path)
+	if err != nil {
+		if !IsErrNotExist(err) {
+			return nil, err
+		}
+		fi = nil
+	} else if !fi.Mode().IsRegular() {
+		return nil, NewPathError("open", name, ErrNotFile)
+	}
+	// a new file is created with the given permissions subject
+	// to the umask, the mode of an existing file is copied on Close.
+	tperm := perm
+	if fi != nil {
+		tperm = 0600
+	}
+	f, err := tempFile(fs, Dir(fs, path), "."+Base(fs, path)+".*.tmp", tperm)
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicWriter{
+		fs:     fs,
+		name:   path,
+		file:   f,
+		tmp:    Join(fs, Dir(fs, path), Base(fs, f.Name())),
+		target: fi,
+	}, nil
+}
+
+// Name returns the (symlink resolved) path of the target file.
+func (w *AtomicWriter) Name() string {
+	return w.name
+}
+
+func (w *AtomicWriter) Write(data []byte) (int, error) {
+	if w.done {
+		return 0, NewPathError("write", w.name, os.ErrClosed)
+	}
+	return w.file.Write(data)
+}
+
+// Close syncs the written content and replaces the target file.
+func (w *AtomicWriter) Close() error {
+	if w.done {
+		return NewPathError("close", w.name, os.ErrClosed)
+	}
+	w.done = true
+
+	err := w.file.Sync()
+	if err1 := w.file.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = w.setAttributes()
+	}
+	if err == nil {
+		err = w.fs.Rename(w.tmp, w.name)
+	}
+	if err != nil {
+		w.fs.Remove(w.tmp)
+		return err
+	}
+	syncDir(w.fs, Dir(w.fs, w.name))
+	return nil
+}
+
+// Abort discards the written content, if the writer
+// is not already closed.
+func (w *AtomicWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.file.Close()
+	return w.fs.Remove(w.tmp)
+}
+
+// setAttributes copies the mode and ownership of an existing
+// target file. Changing the ownership is done on a best effort
+// basis, because it typically requires privileges.
+func (w *AtomicWriter) setAttributes() error {
+	if w.target == nil {
+		return nil
+	}
+	err := w.fs.Chmod(w.tmp, w.target.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+	if err != nil {
+		return err
+	}
+	if uid, gid, ok := Owner(w.target); ok {
+		err = Chown(w.fs, w.tmp, uid, gid)
+		if err != nil && !IsErrNotSupported(err) && !IsErrPermission(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDir persists a directory, if the filesystem
+// supports it. Errors are ignored, because not all
+// platforms support syncing directories.
+func syncDir(fs FileSystem, dir string) {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return
+	}
+	f.Sync()
+	f.Close()
+}
+
+// WriteFileAtomic writes data to the named file like WriteFile,
+// but atomically replaces the file content using an AtomicWriter.
+func WriteFileAtomic(fs FileSystem, filename string, data []byte, mode FileMode) error {
+	w, err := NewAtomicWriter(fs, filename, mode)
+	if err != nil {
+		return err
+	}
+	defer w.Abort()
+	n, err := w.Write(data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"context"
+	"io"
+)
+
+// ContextFileSystem is an optional interface for filesystems supporting
+// the cancellation of operations by a context.Context, for example
+// filesystems based on remote backends.
+// Implementations should return the error of the context if it is
+// cancelled before or while an operation is executed.
+// Plain filesystems can be adapted with AsContextFileSystem.
+type ContextFileSystem interface {
+	FileSystem
+
+	CreateContext(ctx context.Context, name string) (File, error)
+	OpenContext(ctx context.Context, name string) (File, error)
+	OpenFileContext(ctx context.Context, name string, flags int, perm FileMode) (File, error)
+	StatContext(ctx context.Context, name string) (FileInfo, error)
+	LstatContext(ctx context.Context, name string) (FileInfo, error)
+	// ReadDirContext returns the entries of the named directory
+	// sorted by name (see ReadDirEntries).
+	ReadDirContext(ctx context.Context, name string) ([]DirEntry, error)
+	MkdirContext(ctx context.Context, name string, perm FileMode) error
+	MkdirAllContext(ctx context.Context, path string, perm FileMode) error
+	RemoveContext(ctx context.Context, name string) error
+	RemoveAllContext(ctx context.Context, path string) error
+	RenameContext(ctx context.Context, oldname, newname string) error
+}
+
+// AsContextFileSystem provides a ContextFileSystem for a filesystem.
+// If the filesystem does not support contexts, the returned adapter
+// checks the context before every operation. ReadDirContext
+// checks it again after reading the directory and RemoveAllContext
+// additionally checks it for every removed entry.
+func AsContextFileSystem(fs FileSystem) ContextFileSystem {
+	if c, ok := fs.(ContextFileSystem); ok {
+		return c
+	}
+	return &contextFileSystem{fs}
+}
+
+type contextFileSystem struct {
+	FileSystem
+}
+
+var _ ContextFileSystem = (*contextFileSystem)(nil)
+
+func (c *contextFileSystem) CreateContext(ctx context.Context, name string) (File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Create(name)
+}
+
+func (c *contextFileSystem) OpenContext(ctx context.Context, name string) (File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Open(name)
+}
+
+func (c *contextFileSystem) OpenFileContext(ctx context.Context, name string, flags int, perm FileMode) (File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.OpenFile(name, flags, perm)
+}
+
+func (c *contextFileSystem) StatContext(ctx context.Context, name string) (FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Stat(name)
+}
+
+func (c *contextFileSystem) LstatContext(ctx context.Context, name string) (FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Lstat(name)
+}
+
+func (c *contextFileSystem) ReadDirContext(ctx context.Context, name string) ([]DirEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := ReadDirEntries(c.FileSystem, name)
+	if err != nil {
+		return nil, err
+	}
+	return entries, ctx.Err()
+}
+
+func (c *contextFileSystem) MkdirContext(ctx context.Context, name string, perm FileMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Mkdir(name, perm)
+}
+
+func (c *contextFileSystem) MkdirAllContext(ctx context.Context, path string, perm FileMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.MkdirAll(path, perm)
+}
+
+func (c *contextFileSystem) RemoveContext(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Remove(name)
+}
+
+func (c *contextFileSystem) RemoveAllContext(ctx context.Context, path string) error {
+	return removeAll(ctx, c.FileSystem, path)
+}
+
+func (c *contextFileSystem) RenameContext(ctx context.Context, oldname, newname string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Rename(oldname, newname)
+}
+
+// removeAll removes a directory tree entry by entry
+// to be able to check the context in between.
+func removeAll(ctx context.Context, fs FileSystem, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		if IsErrNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.IsDir() {
+		entries, err := ReadDirEntries(fs, path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := removeAll(ctx, fs, Join(fs, path, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	err = fs.Remove(path)
+	if err != nil && !IsErrNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CreateContext creates a file, if the context is not cancelled.
+func CreateContext(ctx context.Context, fs FileSystem, name string) (File, error) {
+	return AsContextFileSystem(fs).CreateContext(ctx, name)
+}
+
+// OpenContext opens a file for reading, if the context is not cancelled.
+func OpenContext(ctx context.Context, fs FileSystem, name string) (File, error) {
+	return AsContextFileSystem(fs).OpenContext(ctx, name)
+}
+
+// OpenFileContext opens a file, if the context is not cancelled.
+func OpenFileContext(ctx context.Context, fs FileSystem, name string, flags int, perm FileMode) (File, error) {
+	return AsContextFileSystem(fs).OpenFileContext(ctx, name, flags, perm)
+}
+
+// StatContext returns the FileInfo describing the named file,
+// if the context is not cancelled.
+func StatContext(ctx context.Context, fs FileSystem, name string) (FileInfo, error) {
+	return AsContextFileSystem(fs).StatContext(ctx, name)
+}
+
+// LstatContext returns the FileInfo describing the named file
+// without following symbolic links, if the context is not cancelled.
+func LstatContext(ctx context.Context, fs FileSystem, name string) (FileInfo, error) {
+	return AsContextFileSystem(fs).LstatContext(ctx, name)
+}
+
+// ReadDirContext returns the sorted entries of a directory,
+// if the context is not cancelled.
+func ReadDirContext(ctx context.Context, fs FileSystem, name string) ([]DirEntry, error) {
+	return AsContextFileSystem(fs).ReadDirContext(ctx, name)
+}
+
+// MkdirContext creates a directory, if the context is not cancelled.
+func MkdirContext(ctx context.Context, fs FileSystem, name string, perm FileMode) error {
+	return AsContextFileSystem(fs).MkdirContext(ctx, name, perm)
+}
+
+// MkdirAllContext creates a directory path, if the context is not cancelled.
+func MkdirAllContext(ctx context.Context, fs FileSystem, path string, perm FileMode) error {
+	return AsContextFileSystem(fs).MkdirAllContext(ctx, path, perm)
+}
+
+// RemoveContext removes a file or empty directory,
+// if the context is not cancelled.
+func RemoveContext(ctx context.Context, fs FileSystem, name string) error {
+	return AsContextFileSystem(fs).RemoveContext(ctx, name)
+}
+
+// RemoveAllContext removes a path and any children it contains like
+// RemoveAll. For filesystems not supporting contexts, the entries are
+// removed one by one and the removal stops if the context is cancelled.
+func RemoveAllContext(ctx context.Context, fs FileSystem, path string) error {
+	return AsContextFileSystem(fs).RemoveAllContext(ctx, path)
+}
+
+// RenameContext renames a file, if the context is not cancelled.
+func RenameContext(ctx context.Context, fs FileSystem, oldname, newname string) error {
+	return AsContextFileSystem(fs).RenameContext(ctx, oldname, newname)
+}
+
+// contextReader is a reader failing with the error
+// of the context if the context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// recordingFS is a ContextFileSystem recording the
+// contexts passed to StatContext.
+type recordingFS struct {
+	ContextFileSystem
+	ctxs []context.Context
+}
+
+func (r *recordingFS) StatContext(ctx context.Context, name string) (FileInfo, error) {
+	r.ctxs = append(r.ctxs, ctx)
+	return r.ContextFileSystem.StatContext(ctx, name)
+}
+
+type contextKey struct{}
+
+// cancellingFS cancels a context after the first removal.
+type cancellingFS struct {
+	FileSystem
+	cancel context.CancelFunc
+}
+
+func (c *cancellingFS) Remove(name string) error {
+	c.cancel()
+	return c.FileSystem.Remove(name)
+}
+
+var _ = Describe("context", func() {
+	var fs VFS
+	var cancelled context.Context
+
+	BeforeEach(func() {
+		fs = New(memoryfs.New())
+		Expect(fs.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(fs, "/d1/a", []byte("a"), nil)
+		ExpectFileCreate(fs, "/d1/d2/b", []byte("b"), nil)
+
+		var cancel context.CancelFunc
+		cancelled, cancel = context.WithCancel(context.Background())
+		cancel()
+	})
+
+	Context("adapter", func() {
+		It("executes operations", func() {
+			cfs := AsContextFileSystem(memoryfs.New())
+			ctx := context.Background()
+			Expect(cfs.MkdirAllContext(ctx, "/d1/d2", os.ModePerm)).To(Succeed())
+			f, err := cfs.CreateContext(ctx, "/d1/a")
+			Expect(err).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+			Expect(cfs.RenameContext(ctx, "/d1/a", "/d1/b")).To(Succeed())
+			fi, err := cfs.StatContext(ctx, "/d1/b")
+			Expect(err).To(Succeed())
+			Expect(fi.Mode().IsRegular()).To(BeTrue())
+			entries, err := cfs.ReadDirContext(ctx, "/d1")
+			Expect(err).To(Succeed())
+			Expect(len(entries)).To(Equal(2))
+			Expect(entries[0].Name()).To(Equal("b"))
+			Expect(cfs.RemoveAllContext(ctx, "/d1")).To(Succeed())
+			Expect(Exists(cfs, "/d1")).To(BeFalse())
+		})
+
+		It("checks cancellation", func() {
+			_, err := OpenContext(cancelled, fs, "/d1/a")
+			Expect(err).To(MatchError(context.Canceled))
+			_, err = StatContext(cancelled, fs, "/d1/a")
+			Expect(err).To(MatchError(context.Canceled))
+			_, err = ReadDirContext(cancelled, fs, "/d1")
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(MkdirContext(cancelled, fs, "/d3", os.ModePerm)).To(MatchError(context.Canceled))
+			Expect(RemoveContext(cancelled, fs, "/d1/a")).To(MatchError(context.Canceled))
+			Expect(fs.Exists("/d1/a")).To(BeTrue())
+			Expect(fs.Exists("/d3")).To(BeFalse())
+		})
+
+		It("uses context filesystems", func() {
+			r := &recordingFS{ContextFileSystem: AsContextFileSystem(fs)}
+			Expect(AsContextFileSystem(r)).To(BeIdenticalTo(r))
+
+			ctx := context.WithValue(context.Background(), contextKey{}, "value")
+			_, err := New(r).StatContext(ctx, "/d1/a")
+			Expect(err).To(Succeed())
+			Expect(r.ctxs).To(Equal([]context.Context{ctx}))
+		})
+	})
+
+	Context("RemoveAll", func() {
+		It("stops on cancellation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := RemoveAllContext(ctx, &cancellingFS{fs, cancel}, "/d1")
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(fs.Exists("/d1/d2")).To(BeTrue())
+			Expect(fs.Exists("/d1/a")).To(BeFalse())
+		})
+	})
+
+	Context("Walk", func() {
+		It("stops on cancellation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var found []string
+			err := WalkContext(ctx, fs, "/d1", func(path string, info FileInfo, err error) error {
+				found = append(found, path)
+				if path == "/d1/a" {
+					cancel()
+				}
+				return err
+			})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(found).To(Equal([]string{"/d1", "/d1/a"}))
+		})
+	})
+
+	Context("Copy", func() {
+		It("does not start if cancelled", func() {
+			Expect(CopyDirContext(cancelled, fs, "/d1", fs, "/d3")).To(MatchError(context.Canceled))
+			Expect(fs.Exists("/d3")).To(BeFalse())
+		})
+
+		It("stops on cancellation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var found []string
+			err := CopyContext(ctx, fs, "/d1", fs, "/d3", &CopyOptions{
+				ContinueOnError: true,
+				Progress: func(p CopyProgress) {
+					found = append(found, p.Path)
+					if p.Path == "a" {
+						cancel()
+					}
+				},
+			})
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(found).To(Equal([]string{".", "a"}))
+			Expect(fs.Exists("/d3/a")).To(BeTrue())
+			Expect(fs.Exists("/d3/d2")).To(BeFalse())
+		})
+
+		It("copies with context", func() {
+			Expect(CopyDirContext(context.Background(), fs, "/d1", fs, "/d3")).To(Succeed())
+			Expect(fs.ReadFile("/d3/d2/b")).To(Equal([]byte("b")))
+		})
+	})
+})
@@ -0,0 +1,470 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SymlinkMode describes how Copy handles symbolic links.
+type SymlinkMode int
+
+const (
+	// SymlinkPreserve copies symbolic links as symbolic links.
+	SymlinkPreserve SymlinkMode = iota
+	// SymlinkFollow copies the entries symbolic links refer to.
+	SymlinkFollow
+)
+
+// OverwriteMode describes how Copy handles already
+// existing non-directory entries in the target.
+type OverwriteMode int
+
+const (
+	// Overwrite replaces existing entries.
+	Overwrite OverwriteMode = iota
+	// OverwriteSkip keeps existing entries.
+	OverwriteSkip
+	// OverwriteIfNewer replaces existing entries, only if
+	// the source entry has a newer modification time.
+	OverwriteIfNewer
+)
+
+// CopyProgress describes a handled entry reported
+// to the progress callback of Copy.
+type CopyProgress struct {
+	// Path is the slash separated path of the entry relative
+	// to the copied source ("." for the source itself).
+	Path string
+	// Info is the file info of the source entry.
+	Info FileInfo
+	// Bytes is the number of copied bytes for regular files.
+	Bytes int64
+	// Skipped is set for entries kept by the OverwriteMode.
+	Skipped bool
+	// Error is the error encountered for the entry.
+	Error error
+}
+
+// CopyOptions describes the behaviour of Copy.
+// The zero value preserves symbolic links, overwrites existing
+// entries and preserves extended attributes (on a best effort
+// basis), only.
+type CopyOptions struct {
+	// Symlinks describes how to handle symbolic links.
+	Symlinks SymlinkMode
+	// Overwrite describes how to handle existing target entries.
+	Overwrite OverwriteMode
+	// Mode describes additional aspects of entries to be preserved.
+	Mode CopyMode
+
+	// Include is a list of patterns (see MatchList) selecting
+	// non-directory entries to copy. Patterns containing a slash
+	// are matched against the relative path, others against the
+	// base name. If empty, all entries are copied.
+	Include []string
+	// Exclude is a list of patterns (like for Include) for
+	// entries (including directories) not to copy.
+	Exclude []string
+	// Filter is an optional function deciding whether an
+	// entry (given by its relative path) should be copied.
+	Filter func(path string, fi FileInfo) bool
+
+	// Progress is an optional callback called for every handled
+	// entry. The calls are serialized.
+	Progress func(CopyProgress)
+
+	// Workers is the number of parallel workers used to copy
+	// non-directory entries.
+	Workers int
+	// ContinueOnError continues the copy operation after errors
+	// and returns all errors joined with errors.Join.
+	ContinueOnError bool
+}
+
+// Copy copies a filesystem entry (for directories the complete tree)
+// to the given destination path according to the given options
+// (if nil, the defaults described by CopyOptions are used).
+// Existing directories are merged.
+func Copy(srcfs FileSystem, src string, dstfs FileSystem, dst string, opts *CopyOptions) error {
+	return CopyContext(context.Background(), srcfs, src, dstfs, dst, opts)
+}
+
+// CopyContext copies a filesystem entry like Copy. The operation is
+// aborted with the error of the context, if the context is cancelled.
+// Already copied entries are kept.
+func CopyContext(ctx context.Context, srcfs FileSystem, src string, dstfs FileSystem, dst string, opts *CopyOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c := &copier{ctx: ctx, srcfs: srcfs, dstfs: dstfs, ancestors: map[string]struct{}{}}
+	if opts != nil {
+		c.opts = *opts
+	}
+	for _, list := range [][]string{c.opts.Include, c.opts.Exclude} {
+		for _, p := range list {
+			if _, err := Match(strings.TrimPrefix(p, "!"), ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+
+	src = Trim(srcfs, src)
+	dst = Trim(dstfs, dst)
+	fi, err := c.stat(src)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		err = MkdirAllContext(ctx, dstfs, dst, os.ModePerm)
+		if err == nil {
+			c.copyDir(src, dst, ".", fi)
+		} else {
+			c.handle(".", fi, 0, err)
+		}
+	} else {
+		c.submit(func() { c.copyEntry(src, dst, ".", fi) })
+	}
+	c.wait()
+	for i := len(c.dirs) - 1; i >= 0; i-- {
+		d := c.dirs[i]
+		if c.aborted() {
+			break
+		}
+		if err := c.finish(d.fi, d.path); err != nil {
+			c.handle(d.rel, d.fi, 0, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		c.errs = append([]error{err}, c.errs...)
+	}
+	if c.opts.ContinueOnError {
+		return errors.Join(c.errs...)
+	}
+	if len(c.errs) > 0 {
+		return c.errs[0]
+	}
+	return nil
+}
+
+type copyDir struct {
+	path string
+	rel  string
+	fi   FileInfo
+}
+
+type copier struct {
+	ctx   context.Context
+	srcfs FileSystem
+	dstfs FileSystem
+	opts  CopyOptions
+
+	lock   sync.Mutex
+	errs   []error
+	failed atomic.Bool
+
+	jobs chan func()
+	wg   sync.WaitGroup
+
+	dirs      []copyDir
+	ancestors map[string]struct{}
+}
+
+func (c *copier) stat(path string) (FileInfo, error) {
+	if c.opts.Symlinks == SymlinkFollow {
+		return c.srcfs.Stat(path)
+	}
+	return c.srcfs.Lstat(path)
+}
+
+func (c *copier) aborted() bool {
+	return c.failed.Load() || c.ctx.Err() != nil
+}
+
+// handle records the result for an entry. It reports
+// whether the entry has been handled successfully.
+func (c *copier) handle(rel string, fi FileInfo, n int64, err error, skipped ...bool) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil {
+		c.errs = append(c.errs, err)
+		if !c.opts.ContinueOnError {
+			c.failed.Store(true)
+		}
+	}
+	if c.opts.Progress != nil {
+		c.opts.Progress(CopyProgress{
+			Path:    rel,
+			Info:    fi,
+			Bytes:   n,
+			Skipped: len(skipped) > 0 && skipped[0],
+			Error:   err,
+		})
+	}
+	return err == nil
+}
+
+func (c *copier) submit(job func()) {
+	if c.opts.Workers <= 1 {
+		job()
+		return
+	}
+	if c.jobs == nil {
+		c.jobs = make(chan func())
+		for i := 0; i < c.opts.Workers; i++ {
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				for job := range c.jobs {
+					if !c.aborted() {
+						job()
+					}
+				}
+			}()
+		}
+	}
+	c.jobs <- job
+}
+
+func (c *copier) wait() {
+	if c.jobs != nil {
+		close(c.jobs)
+		c.wg.Wait()
+	}
+}
+
+func (c *copier) selected(rel string, fi FileInfo) bool {
+	if matchPatterns(c.opts.Exclude, rel) {
+		return false
+	}
+	if len(c.opts.Include) > 0 && !fi.IsDir() && !matchPatterns(c.opts.Include, rel) {
+		return false
+	}
+	return c.opts.Filter == nil || c.opts.Filter(rel, fi)
+}
+
+// matchPatterns matches a relative path against a pattern list
+// like MatchList, but patterns without a slash are matched
+// against the base name.
+func matchPatterns(patterns []string, rel string) bool {
+	matched := false
+	for _, p := range patterns {
+		neg := strings.HasPrefix(p, "!")
+		if neg {
+			p = p[1:]
+		}
+		name := rel
+		if !strings.Contains(p, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := Match(p, name); ok {
+			matched = !neg
+		}
+	}
+	return matched
+}
+
+func (c *copier) copyDir(src, dst, rel string, fi FileInfo) {
+	if c.opts.Symlinks == SymlinkFollow {
+		canon, err := Canonical(c.srcfs, src, true)
+		if err != nil {
+			c.handle(rel, fi, 0, err)
+			return
+		}
+		if _, ok := c.ancestors[canon]; ok {
+			c.handle(rel, fi, 0, NewPathError("copy", src, ErrSymlinkLoop))
+			return
+		}
+		c.ancestors[canon] = struct{}{}
+		defer delete(c.ancestors, canon)
+	}
+
+	if !c.handle(rel, fi, 0, c.mkdir(src, dst, fi)) {
+		return
+	}
+	c.dirs = append(c.dirs, copyDir{path: dst, rel: rel, fi: fi})
+
+	entries, err := ReadDir(c.srcfs, src)
+	if err != nil {
+		c.handle(rel, fi, 0, err)
+		return
+	}
+	for _, e := range entries {
+		if c.aborted() {
+			return
+		}
+		srcPath := Join(c.srcfs, src, e.Name())
+		dstPath := Join(c.dstfs, dst, e.Name())
+		erel := path.Join(rel, e.Name())
+		if c.opts.Symlinks == SymlinkFollow && e.Mode()&os.ModeSymlink != 0 {
+			e, err = c.srcfs.Stat(srcPath)
+			if err != nil {
+				c.handle(erel, e, 0, err)
+				continue
+			}
+		}
+		if !c.selected(erel, e) {
+			continue
+		}
+		if e.IsDir() {
+			c.copyDir(srcPath, dstPath, erel, e)
+		} else {
+			fi := e
+			c.submit(func() { c.copyEntry(srcPath, dstPath, erel, fi) })
+		}
+	}
+}
+
+func (c *copier) mkdir(src, dst string, fi FileInfo) error {
+	di, err := c.dstfs.Lstat(dst)
+	if err == nil {
+		if !di.IsDir() {
+			return NewPathError("copy", dst, ErrNotDir)
+		}
+	} else {
+		if !IsErrNotExist(err) {
+			return err
+		}
+		err = c.dstfs.Mkdir(dst, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+	return c.metadata(src, dst, fi)
+}
+
+// metadata preserves the extended attributes (on a best effort
+// basis, for example security.* attributes require privileges)
+// and the ownership of an entry.
+func (c *copier) metadata(src, dst string, fi FileInfo) error {
+	if err := CopyXattrs(c.srcfs, src, c.dstfs, dst); err != nil && !IsErrPermission(err) && !IsErrNotSupported(err) {
+		return err
+	}
+	return copyOwnership(fi, c.dstfs, dst, c.opts.Mode)
+}
+
+// finish preserves the permissions and times of a directory
+// after its content has been copied.
+func (c *copier) finish(fi FileInfo, dst string) error {
+	if c.opts.Mode&CopyPermissions != 0 {
+		if err := c.dstfs.Chmod(dst, fi.Mode()); err != nil {
+			return err
+		}
+	}
+	return copyTimes(fi, c.dstfs, dst, c.opts.Mode)
+}
+
+func (c *copier) copyEntry(src, dst, rel string, fi FileInfo) {
+	di, err := c.dstfs.Lstat(dst)
+	if err == nil {
+		if di.IsDir() {
+			c.handle(rel, fi, 0, NewPathError("copy", dst, ErrExist))
+			return
+		}
+		switch c.opts.Overwrite {
+		case OverwriteSkip:
+			c.handle(rel, fi, 0, nil, true)
+			return
+		case OverwriteIfNewer:
+			if !fi.ModTime().After(di.ModTime()) {
+				c.handle(rel, fi, 0, nil, true)
+				return
+			}
+		}
+		if !di.Mode().IsRegular() || !fi.Mode().IsRegular() {
+			if err := c.dstfs.Remove(dst); err != nil {
+				c.handle(rel, fi, 0, err)
+				return
+			}
+		}
+	} else if !IsErrNotExist(err) {
+		c.handle(rel, fi, 0, err)
+		return
+	}
+
+	var n int64
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		err = c.copySymlink(src, dst, fi)
+	case fi.Mode().IsRegular():
+		n, err = c.copyFile(src, dst, fi)
+	default:
+		err = NewPathError("copy", src, errors.New("file type not supported"))
+	}
+	c.handle(rel, fi, n, err)
+}
+
+func (c *copier) copySymlink(src, dst string, fi FileInfo) error {
+	old, err := c.srcfs.Readlink(src)
+	if err != nil {
+		return err
+	}
+	err = c.dstfs.Symlink(old, dst)
+	if err != nil {
+		return err
+	}
+	return c.metadata(src, dst, fi)
+}
+
+func (c *copier) copyFile(src, dst string, fi FileInfo) (int64, error) {
+	s, err := OpenContext(c.ctx, c.srcfs, src)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	perm := os.FileMode(0o666)
+	if c.opts.Mode&CopyPermissions != 0 {
+		perm = fi.Mode() & os.ModePerm
+	}
+	d, err := OpenFileContext(c.ctx, c.dstfs, dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, err
+	}
+	var r io.Reader = s
+	if c.ctx.Done() != nil {
+		r = &contextReader{c.ctx, s}
+	}
+	n, err := io.Copy(d, r)
+	if err1 := d.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return n, err
+	}
+	if c.opts.Mode&CopyPermissions != 0 {
+		err = c.dstfs.Chmod(dst, fi.Mode())
+		if err != nil {
+			return n, err
+		}
+	}
+	err = c.metadata(src, dst, fi)
+	if err != nil {
+		return n, err
+	}
+	return n, copyTimes(fi, c.dstfs, dst, c.opts.Mode)
+}
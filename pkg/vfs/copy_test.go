@@ -0,0 +1,214 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("copy", func() {
+	var fs FileSystem
+	var dst FileSystem
+	var mtime time.Time
+
+	BeforeEach(func() {
+		fs = memoryfs.New()
+		dst = memoryfs.New()
+		mtime = time.Now().Add(-time.Hour).Truncate(time.Second)
+		Expect(fs.MkdirAll("/src/d1/d2", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(fs, "/src/d1/f1.txt", []byte("content"), nil)
+		ExpectFileCreate(fs, "/src/d1/d2/f2.log", []byte("log"), nil)
+		ExpectFileCreate(fs, "/src/f3.txt", []byte("other"), nil)
+		Expect(fs.Symlink("d1", "/src/link")).To(Succeed())
+		Expect(fs.Chmod("/src/d1/f1.txt", 0o640)).To(Succeed())
+		Expect(fs.Chtimes("/src/d1/f1.txt", mtime, mtime)).To(Succeed())
+		Expect(fs.Chmod("/src/d1", 0o750)).To(Succeed())
+		Expect(fs.Chtimes("/src/d1", mtime, mtime)).To(Succeed())
+	})
+
+	It("copies tree with defaults", func() {
+		Expect(Copy(fs, "/src", dst, "/dst", nil)).To(Succeed())
+		ExpectFolders(dst, "/dst", []string{"d1", "f3.txt", "link"}, nil)
+		ExpectFileContent(dst, "/dst/d1/d2/f2.log", "log")
+		Expect(dst.Readlink("/dst/link")).To(Equal("d1"))
+		fi, err := dst.Stat("/dst/d1/f1.txt")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o666)))
+	})
+
+	It("copies single file", func() {
+		Expect(Copy(fs, "/src/d1/f1.txt", dst, "/f1", nil)).To(Succeed())
+		ExpectFileContent(dst, "/f1", "content")
+	})
+
+	It("preserves permissions and times", func() {
+		Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Mode: CopyPermissions | CopyTimes})).To(Succeed())
+		fi, err := dst.Stat("/dst/d1/f1.txt")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+		Expect(fi.ModTime()).To(Equal(mtime))
+		fi, err = dst.Stat("/dst/d1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o750)))
+		Expect(fi.ModTime()).To(Equal(mtime))
+	})
+
+	It("follows symbolic links", func() {
+		Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Symlinks: SymlinkFollow})).To(Succeed())
+		fi, err := dst.Lstat("/dst/link")
+		Expect(err).To(Succeed())
+		Expect(fi.IsDir()).To(BeTrue())
+		ExpectFileContent(dst, "/dst/link/d2/f2.log", "log")
+	})
+
+	It("detects symbolic link loops", func() {
+		Expect(fs.Symlink("..", "/src/d1/d2/loop")).To(Succeed())
+		err := Copy(fs, "/src", dst, "/dst", &CopyOptions{Symlinks: SymlinkFollow})
+		Expect(err).To(MatchError(ContainSubstring("symbolic link loop")))
+	})
+
+	Context("overwrite", func() {
+		BeforeEach(func() {
+			Expect(dst.MkdirAll("/dst/d1", os.ModePerm)).To(Succeed())
+			ExpectFileCreate(dst, "/dst/d1/f1.txt", []byte("old"), nil)
+			ExpectFileCreate(dst, "/dst/f3.txt", []byte("old"), nil)
+			Expect(dst.Chtimes("/dst/d1/f1.txt", mtime.Add(-time.Hour), mtime.Add(-time.Hour))).To(Succeed())
+		})
+
+		It("overwrites existing files", func() {
+			Expect(Copy(fs, "/src", dst, "/dst", nil)).To(Succeed())
+			ExpectFileContent(dst, "/dst/d1/f1.txt", "content")
+			ExpectFileContent(dst, "/dst/f3.txt", "other")
+		})
+
+		It("skips existing files", func() {
+			var skipped []string
+			opts := &CopyOptions{
+				Overwrite: OverwriteSkip,
+				Progress: func(p CopyProgress) {
+					if p.Skipped {
+						skipped = append(skipped, p.Path)
+					}
+				},
+			}
+			Expect(Copy(fs, "/src", dst, "/dst", opts)).To(Succeed())
+			ExpectFileContent(dst, "/dst/d1/f1.txt", "old")
+			ExpectFileContent(dst, "/dst/f3.txt", "old")
+			ExpectFileContent(dst, "/dst/d1/d2/f2.log", "log")
+			sort.Strings(skipped)
+			Expect(skipped).To(Equal([]string{"d1/f1.txt", "f3.txt"}))
+		})
+
+		It("updates older files", func() {
+			Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Overwrite: OverwriteIfNewer})).To(Succeed())
+			ExpectFileContent(dst, "/dst/d1/f1.txt", "content")
+			ExpectFileContent(dst, "/dst/f3.txt", "old")
+		})
+	})
+
+	Context("filter", func() {
+		It("includes matching files", func() {
+			Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Include: []string{"*.txt"}})).To(Succeed())
+			ExpectFolders(dst, "/dst", []string{"d1", "f3.txt"}, nil)
+			ExpectFolders(dst, "/dst/d1", []string{"d2", "f1.txt"}, nil)
+			ExpectFolders(dst, "/dst/d1/d2", nil, nil)
+		})
+
+		It("excludes matching entries", func() {
+			Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Exclude: []string{"d1/d2", "link"}})).To(Succeed())
+			ExpectFolders(dst, "/dst", []string{"d1", "f3.txt"}, nil)
+			ExpectFolders(dst, "/dst/d1", []string{"f1.txt"}, nil)
+		})
+
+		It("supports negated and recursive patterns", func() {
+			opts := &CopyOptions{Exclude: []string{"*.txt", "!f1.txt", "d1/**/*.log"}}
+			Expect(Copy(fs, "/src", dst, "/dst", opts)).To(Succeed())
+			ExpectFolders(dst, "/dst", []string{"d1", "link"}, nil)
+			ExpectFolders(dst, "/dst/d1", []string{"d2", "f1.txt"}, nil)
+			ExpectFolders(dst, "/dst/d1/d2", nil, nil)
+		})
+
+		It("uses filter function", func() {
+			opts := &CopyOptions{
+				Filter: func(path string, fi FileInfo) bool { return fi.IsDir() || fi.Size() > 3 },
+			}
+			Expect(Copy(fs, "/src", dst, "/dst", opts)).To(Succeed())
+			ExpectFolders(dst, "/dst", []string{"d1", "f3.txt"}, nil)
+			ExpectFolders(dst, "/dst/d1/d2", nil, nil)
+		})
+
+		It("rejects invalid patterns", func() {
+			Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Exclude: []string{"["}})).NotTo(Succeed())
+		})
+	})
+
+	It("reports progress", func() {
+		var lock sync.Mutex
+		var paths []string
+		var bytes int64
+		opts := &CopyOptions{
+			Workers: 4,
+			Progress: func(p CopyProgress) {
+				lock.Lock()
+				defer lock.Unlock()
+				paths = append(paths, p.Path)
+				bytes += p.Bytes
+			},
+		}
+		Expect(Copy(fs, "/src", dst, "/dst", opts)).To(Succeed())
+		sort.Strings(paths)
+		Expect(paths).To(Equal([]string{".", "d1", "d1/d2", "d1/d2/f2.log", "d1/f1.txt", "f3.txt", "link"}))
+		Expect(bytes).To(Equal(int64(15)))
+	})
+
+	It("copies with parallel workers", func() {
+		for i := 0; i < 50; i++ {
+			ExpectFileCreate(fs, Join(fs, "/src/d1/d2", string(rune('a'+i%26))+string(rune('a'+i/26))), []byte("data"), nil)
+		}
+		Expect(Copy(fs, "/src", dst, "/dst", &CopyOptions{Workers: 8})).To(Succeed())
+		list, err := ReadDir(dst, "/dst/d1/d2")
+		Expect(err).To(Succeed())
+		Expect(list).To(HaveLen(51))
+	})
+
+	It("collects errors", func() {
+		Expect(dst.MkdirAll("/dst/d1/f1.txt", os.ModePerm)).To(Succeed())
+		Expect(dst.MkdirAll("/dst/f3.txt", os.ModePerm)).To(Succeed())
+		err := Copy(fs, "/src", dst, "/dst", &CopyOptions{ContinueOnError: true})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(interface{ Unwrap() []error }).Unwrap()).To(HaveLen(2))
+		ExpectFileContent(dst, "/dst/d1/d2/f2.log", "log")
+	})
+
+	It("aborts on first error", func() {
+		Expect(dst.MkdirAll("/dst/d1/f1.txt", os.ModePerm)).To(Succeed())
+		Expect(Copy(fs, "/src", dst, "/dst", nil)).To(MatchError(ContainSubstring("f1.txt")))
+		Expect(Exists(dst, "/dst/f3.txt")).To(BeFalse())
+	})
+})
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// DiffKind describes the kind of difference of a filesystem entry.
+type DiffKind int
+
+const (
+	// DiffAdded describes an entry only present in the new tree.
+	DiffAdded DiffKind = iota
+	// DiffRemoved describes an entry only present in the old tree.
+	DiffRemoved
+	// DiffModified describes a file with different content or
+	// a symbolic link with a different target.
+	DiffModified
+	// DiffTypeChanged describes an entry with a different type
+	// (for example a file replaced by a directory).
+	DiffTypeChanged
+	// DiffModeChanged describes an entry with different permissions.
+	DiffModeChanged
+	// DiffTimeChanged describes a regular file with equal content,
+	// but a different modification time (see DiffOptions.Times).
+	DiffTimeChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "A"
+	case DiffRemoved:
+		return "D"
+	case DiffModified:
+		return "M"
+	case DiffTypeChanged:
+		return "T"
+	case DiffModeChanged:
+		return "P"
+	case DiffTimeChanged:
+		return "t"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// DiffEntry describes a difference between two filesystem trees.
+type DiffEntry struct {
+	// Path is the slash separated path of the entry relative
+	// to the compared roots ("." for the roots).
+	Path string
+	Kind DiffKind
+	// Old is the file info of the entry in the old tree, if present.
+	Old FileInfo
+	// New is the file info of the entry in the new tree, if present.
+	New FileInfo
+}
+
+func (e DiffEntry) String() string {
+	return fmt.Sprintf("%s %s", e.Kind, e.Path)
+}
+
+// DiffOptions describes the behaviour of Diff.
+type DiffOptions struct {
+	// ModTime considers regular files with the same size and
+	// modification time as unchanged without comparing the content.
+	ModTime bool
+	// Hash is used to compare the content of regular files with
+	// the same size. The default is sha256.
+	Hash func() hash.Hash
+	// Times reports regular files with equal content, but different
+	// modification times as DiffTimeChanged.
+	Times bool
+}
+
+// Diff walks the trees of two filesystems and reports the differences
+// of the new tree compared to the old one. For removed or added directories
+// only the directory itself is reported. The entries are provided in
+// depth-first order: the entries of a directory are sorted by name and
+// directly follow the directory entry itself (for example a/b is reported
+// before a.txt). Symbolic links are not followed. A root missing in
+// one of the trees is reported as added or removed, if it exists in
+// none of them, an error is returned.
+func Diff(oldfs FileSystem, oldpath string, newfs FileSystem, newpath string, opts *DiffOptions) ([]DiffEntry, error) {
+	d := &differ{oldfs: oldfs, newfs: newfs}
+	if opts != nil {
+		d.opts = *opts
+	}
+	if d.opts.Hash == nil {
+		d.opts.Hash = sha256.New
+	}
+	ofi, err := oldfs.Lstat(oldpath)
+	if err != nil && !IsErrNotExist(err) {
+		return nil, err
+	}
+	nfi, nerr := newfs.Lstat(newpath)
+	if nerr != nil && (!IsErrNotExist(nerr) || err != nil) {
+		return nil, nerr
+	}
+	err = d.compare(oldpath, ofi, newpath, nfi, ".")
+	return d.entries, err
+}
+
+type differ struct {
+	oldfs   FileSystem
+	newfs   FileSystem
+	opts    DiffOptions
+	entries []DiffEntry
+}
+
+func (d *differ) add(rel string, kind DiffKind, ofi, nfi FileInfo) {
+	d.entries = append(d.entries, DiffEntry{Path: rel, Kind: kind, Old: ofi, New: nfi})
+}
+
+func (d *differ) compare(opath string, ofi FileInfo, npath string, nfi FileInfo, rel string) error {
+	switch {
+	case ofi == nil && nfi == nil:
+		return nil
+	case ofi == nil:
+		d.add(rel, DiffAdded, nil, nfi)
+		return nil
+	case nfi == nil:
+		d.add(rel, DiffRemoved, ofi, nil)
+		return nil
+	case ofi.Mode().Type() != nfi.Mode().Type():
+		d.add(rel, DiffTypeChanged, ofi, nfi)
+		return nil
+	}
+
+	switch {
+	case ofi.IsDir():
+		if permissions(ofi) != permissions(nfi) {
+			d.add(rel, DiffModeChanged, ofi, nfi)
+		}
+		return d.compareDir(opath, npath, rel)
+	case ofi.Mode()&os.ModeSymlink != 0:
+		olink, err := d.oldfs.Readlink(opath)
+		if err != nil {
+			return err
+		}
+		nlink, err := d.newfs.Readlink(npath)
+		if err != nil {
+			return err
+		}
+		if olink != nlink {
+			d.add(rel, DiffModified, ofi, nfi)
+		}
+		return nil
+	}
+
+	if ofi.Mode().IsRegular() {
+		equal, err := d.equalContent(opath, ofi, npath, nfi)
+		if err != nil {
+			return err
+		}
+		if !equal {
+			d.add(rel, DiffModified, ofi, nfi)
+		} else if d.opts.Times && !ofi.ModTime().Equal(nfi.ModTime()) {
+			d.add(rel, DiffTimeChanged, ofi, nfi)
+		}
+	}
+	if permissions(ofi) != permissions(nfi) {
+		d.add(rel, DiffModeChanged, ofi, nfi)
+	}
+	return nil
+}
+
+func (d *differ) compareDir(opath, npath, rel string) error {
+	olist, err := ReadDir(d.oldfs, opath)
+	if err != nil {
+		return err
+	}
+	nlist, err := ReadDir(d.newfs, npath)
+	if err != nil {
+		return err
+	}
+	entries := map[string][2]FileInfo{}
+	for _, e := range olist {
+		entries[e.Name()] = [2]FileInfo{e, nil}
+	}
+	for _, e := range nlist {
+		entries[e.Name()] = [2]FileInfo{entries[e.Name()][0], e}
+	}
+	names := make([]string, 0, len(entries))
+	for n := range entries {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		e := entries[n]
+		err := d.compare(Join(d.oldfs, opath, n), e[0], Join(d.newfs, npath, n), e[1], path.Join(rel, n))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) equalContent(opath string, ofi FileInfo, npath string, nfi FileInfo) (bool, error) {
+	if ofi.Size() != nfi.Size() {
+		return false, nil
+	}
+	if d.opts.ModTime && ofi.ModTime().Equal(nfi.ModTime()) {
+		return true, nil
+	}
+	odigest, err := fileDigest(d.oldfs, opath, d.opts.Hash())
+	if err != nil {
+		return false, err
+	}
+	ndigest, err := fileDigest(d.newfs, npath, d.opts.Hash())
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(odigest, ndigest), nil
+}
+
+func permissions(fi FileInfo) FileMode {
+	return fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+func fileDigest(fs FileSystem, path string, h hash.Hash) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
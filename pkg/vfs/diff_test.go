@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+	"github.com/mandelsoft/vfs/pkg/yamlfs"
+)
+
+var _ = Describe("diff", func() {
+	var oldfs FileSystem
+	var newfs FileSystem
+
+	BeforeEach(func() {
+		oldfs = memoryfs.New()
+		Expect(oldfs.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		Expect(oldfs.MkdirAll("/d3", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(oldfs, "/d1/f1", []byte("content"), nil)
+		ExpectFileCreate(oldfs, "/d1/d2/f2", []byte("other"), nil)
+		ExpectFileCreate(oldfs, "/f3", []byte("file"), nil)
+		Expect(oldfs.Symlink("d1/f1", "/link")).To(Succeed())
+
+		newfs = memoryfs.New()
+		Expect(CopyDir(oldfs, "/", newfs, "/", CopyTimes)).To(Succeed())
+	})
+
+	It("reports no differences", func() {
+		Expect(Diff(oldfs, "/", newfs, "/", nil)).To(BeEmpty())
+	})
+
+	It("reports differences", func() {
+		ExpectFileCreate(newfs, "/d1/f1", []byte("changed"), nil)
+		Expect(newfs.RemoveAll("/d1/d2")).To(Succeed())
+		Expect(newfs.Remove("/f3")).To(Succeed())
+		Expect(newfs.Mkdir("/f3", os.ModePerm)).To(Succeed())
+		Expect(newfs.Remove("/link")).To(Succeed())
+		Expect(newfs.Symlink("f3", "/link")).To(Succeed())
+		Expect(newfs.Chmod("/d3", 0o700)).To(Succeed())
+		ExpectFileCreate(newfs, "/d3/f4", []byte("new"), nil)
+
+		list, err := Diff(oldfs, "/", newfs, "/", nil)
+		Expect(err).To(Succeed())
+		var result []string
+		for _, e := range list {
+			result = append(result, e.String())
+		}
+		Expect(result).To(Equal([]string{
+			"D d1/d2",
+			"M d1/f1",
+			"P d3",
+			"A d3/f4",
+			"T f3",
+			"M link",
+		}))
+	})
+
+	It("uses modification time shortcut", func() {
+		ExpectFileCreate(newfs, "/d1/f1", []byte("changed"), nil)
+		fi, err := oldfs.Stat("/d1/f1")
+		Expect(err).To(Succeed())
+		Expect(newfs.Chtimes("/d1/f1", fi.ModTime(), fi.ModTime())).To(Succeed())
+
+		Expect(Diff(oldfs, "/", newfs, "/", &DiffOptions{ModTime: true})).To(BeEmpty())
+		Expect(Diff(oldfs, "/", newfs, "/", nil)).To(HaveLen(1))
+	})
+
+	It("compares different implementations", func() {
+		yfs, err := yamlfs.New(nil)
+		Expect(err).To(Succeed())
+		Expect(CopyDir(oldfs, "/", yfs, "/", CopyTimes)).To(Succeed())
+		ExpectFileCreate(yfs, "/f3", []byte("modified"), nil)
+		list, err := Diff(oldfs, "/", yfs, "/", &DiffOptions{ModTime: true})
+		Expect(err).To(Succeed())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].String()).To(Equal("M f3"))
+	})
+
+	It("handles missing roots", func() {
+		list, err := Diff(oldfs, "/missing", newfs, "/f3", nil)
+		Expect(err).To(Succeed())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].String()).To(Equal("A ."))
+		_, err = Diff(oldfs, "/missing", newfs, "/missing", nil)
+		Expect(IsErrNotExist(err)).To(BeTrue())
+	})
+
+	Context("unified", func() {
+		It("provides unified diff", func() {
+			ExpectFileCreate(oldfs, "/text", []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\n"), nil)
+			ExpectFileCreate(newfs, "/text", []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj"), nil)
+			Expect(UnifiedDiff(oldfs, "/text", newfs, "/text", 1)).To(Equal(`--- a/text
++++ b/text
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -9 +9,2 @@
+ i
++j
+\ No newline at end of file
+`))
+		})
+
+		It("handles missing files", func() {
+			Expect(UnifiedDiff(oldfs, "/missing", newfs, "/f3", 3)).To(Equal(`--- /dev/null
++++ b/f3
+@@ -0,0 +1 @@
++file
+\ No newline at end of file
+`))
+		})
+
+		It("reports equal content", func() {
+			Expect(UnifiedDiff(oldfs, "/f3", newfs, "/f3", 3)).To(Equal(""))
+		})
+
+		It("handles binary content", func() {
+			Expect(Unified("a", []byte{0, 1}, "b", []byte{1}, 3)).To(Equal("Binary files a and b differ\n"))
+		})
+
+		It("handles large content", func() {
+			var o, n, changed strings.Builder
+			for i := 0; i < 10000; i++ {
+				fmt.Fprintf(&o, "old %d\n", i)
+				fmt.Fprintf(&n, "new %d\n", i)
+				if i == 5000 {
+					fmt.Fprintf(&changed, "changed %d\n", i)
+				} else {
+					fmt.Fprintf(&changed, "old %d\n", i)
+				}
+			}
+
+			diff := Unified("a", []byte(o.String()), "b", []byte(n.String()), 3)
+			lines := strings.Split(diff, "\n")
+			Expect(lines[:3]).To(Equal([]string{"--- a", "+++ b", "@@ -1,10000 +1,10000 @@"}))
+			Expect(len(lines)).To(Equal(3 + 20000 + 1))
+
+			diff = Unified("a", []byte(o.String()), "b", []byte(changed.String()), 1)
+			Expect(diff).To(Equal(`--- a
++++ b
+@@ -5000,3 +5000,3 @@
+ old 4999
+-old 5000
++changed 5000
+ old 5001
+`))
+		})
+	})
+
+})
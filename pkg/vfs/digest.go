@@ -0,0 +1,285 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"sync"
+	"time"
+)
+
+// DigestOptions describes the behaviour of TreeDigest.
+type DigestOptions struct {
+	// Hash is the hash used for the digests (for example
+	// sha512.New). The default is sha256.
+	Hash func() hash.Hash
+	// Algorithm is a unique name for the hash function used to
+	// separate the cached digests of different hash functions
+	// (for example sha256.New and sha256.New224). It is required
+	// for using a Cache with a Hash other than the default.
+	Algorithm string
+	// Cache is an optional cache for file digests.
+	Cache *DigestCache
+	// Workers is the number of parallel workers used
+	// to calculate the file digests.
+	Workers int
+}
+
+// FileDigest calculates the digest of the content of a file.
+// If h is nil, sha256 is used.
+func FileDigest(fs FileSystem, path string, h func() hash.Hash) ([]byte, error) {
+	if h == nil {
+		h = sha256.New
+	}
+	return fileDigest(fs, path, h())
+}
+
+// TreeDigest calculates a deterministic Merkle digest for a filesystem
+// entry. The digest of a regular file covers its permissions and content,
+// the digest of a symbolic link its target and the digest of a directory
+// its permissions and the names and digests of all its entries.
+// Symbolic links are not followed. Other file types are not supported.
+// Modification times and ownership are not considered, so equal trees
+// provide the same digest regardless of the filesystem implementation.
+func TreeDigest(fs FileSystem, path string, opts *DigestOptions) ([]byte, error) {
+	d := &digester{fs: fs}
+	if opts != nil {
+		d.opts = *opts
+	}
+	d.alg = d.opts.Algorithm
+	if d.opts.Hash == nil {
+		d.opts.Hash = sha256.New
+		if d.alg == "" {
+			d.alg = "sha256"
+		}
+	}
+	if d.opts.Cache != nil && d.alg == "" {
+		return nil, errors.New("digest cache requires an algorithm name for the hash function")
+	}
+
+	path = Trim(fs, path)
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	n := &digestNode{path: path, fi: fi}
+	d.scan(n)
+	d.wait()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.digest(n), nil
+}
+
+type digestNode struct {
+	path     string
+	fi       FileInfo
+	link     string
+	content  []byte
+	children []*digestNode
+}
+
+type digester struct {
+	fs   FileSystem
+	opts DigestOptions
+	alg  string
+
+	lock sync.Mutex
+	err  error
+	jobs chan *digestNode
+	wg   sync.WaitGroup
+}
+
+func (d *digester) fail(err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *digester) failed() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.err != nil
+}
+
+// scan builds the node tree and schedules the
+// calculation of the file digests.
+func (d *digester) scan(n *digestNode) {
+	if d.failed() {
+		return
+	}
+	switch {
+	case n.fi.IsDir():
+		list, err := ReadDir(d.fs, n.path)
+		if err != nil {
+			d.fail(err)
+			return
+		}
+		for _, fi := range list {
+			c := &digestNode{path: Join(d.fs, n.path, fi.Name()), fi: fi}
+			n.children = append(n.children, c)
+			d.scan(c)
+		}
+	case n.fi.Mode()&os.ModeSymlink != 0:
+		link, err := d.fs.Readlink(n.path)
+		if err != nil {
+			d.fail(err)
+			return
+		}
+		n.link = link
+	case n.fi.Mode().IsRegular():
+		d.submit(n)
+	default:
+		d.fail(NewPathError("digest", n.path, ErrNotSupported))
+	}
+}
+
+func (d *digester) submit(n *digestNode) {
+	if d.opts.Workers <= 1 {
+		d.content(n)
+		return
+	}
+	if d.jobs == nil {
+		d.jobs = make(chan *digestNode)
+		for i := 0; i < d.opts.Workers; i++ {
+			d.wg.Add(1)
+			go func() {
+				defer d.wg.Done()
+				for n := range d.jobs {
+					if !d.failed() {
+						d.content(n)
+					}
+				}
+			}()
+		}
+	}
+	d.jobs <- n
+}
+
+func (d *digester) wait() {
+	if d.jobs != nil {
+		close(d.jobs)
+		d.wg.Wait()
+	}
+}
+
+func (d *digester) content(n *digestNode) {
+	if digest, ok := d.opts.Cache.get(d.fs, n.path, d.alg, n.fi); ok {
+		n.content = digest
+		return
+	}
+	digest, err := fileDigest(d.fs, n.path, d.opts.Hash())
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	d.opts.Cache.set(d.fs, n.path, d.alg, n.fi, digest)
+	n.content = digest
+}
+
+// digest calculates the Merkle digest for a scanned node.
+func (d *digester) digest(n *digestNode) []byte {
+	h := d.opts.Hash()
+	switch {
+	case n.fi.IsDir():
+		fmt.Fprintf(h, "dir\x00%o\x00", permissions(n.fi))
+		for _, c := range n.children {
+			fmt.Fprintf(h, "%s\x00", c.fi.Name())
+			h.Write(d.digest(c))
+		}
+	case n.fi.Mode()&os.ModeSymlink != 0:
+		fmt.Fprintf(h, "symlink\x00%s", n.link)
+	default:
+		fmt.Fprintf(h, "file\x00%o\x00", permissions(n.fi))
+		h.Write(n.content)
+	}
+	return h.Sum(nil)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DigestCache caches file digests for TreeDigest. A cached digest
+// is used as long as the size and modification time of the file
+// are unchanged.
+type DigestCache struct {
+	lock    sync.Mutex
+	entries map[digestKey]digestEntry
+}
+
+type digestKey struct {
+	fs   FileSystem
+	path string
+	alg  string
+}
+
+type digestEntry struct {
+	size    int64
+	modtime time.Time
+	digest  []byte
+}
+
+// NewDigestCache creates a new empty digest cache.
+func NewDigestCache() *DigestCache {
+	return &DigestCache{entries: map[digestKey]digestEntry{}}
+}
+
+// Len provides the number of cached digests.
+func (c *DigestCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.entries)
+}
+
+// Reset removes all cached digests.
+func (c *DigestCache) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = map[digestKey]digestEntry{}
+}
+
+func (c *DigestCache) get(fs FileSystem, path, alg string, fi FileInfo) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[digestKey{fs, path, alg}]
+	if !ok || e.size != fi.Size() || !e.modtime.Equal(fi.ModTime()) {
+		return nil, false
+	}
+	return e.digest, true
+}
+
+func (c *DigestCache) set(fs FileSystem, path, alg string, fi FileInfo, digest []byte) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = map[digestKey]digestEntry{}
+	}
+	c.entries[digestKey{fs, path, alg}] = digestEntry{size: fi.Size(), modtime: fi.ModTime(), digest: digest}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/osfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+	"github.com/mandelsoft/vfs/pkg/yamlfs"
+)
+
+var _ = Describe("digest", func() {
+	var fs FileSystem
+	var digest []byte
+
+	BeforeEach(func() {
+		var err error
+		fs = memoryfs.New()
+		Expect(fs.MkdirAll("/d1/d2", 0o755)).To(Succeed())
+		ExpectFileCreate(fs, "/d1/f1", []byte("content"), nil)
+		ExpectFileCreate(fs, "/d1/d2/f2", []byte("other"), nil)
+		Expect(fs.Chmod("/d1/f1", 0o644)).To(Succeed())
+		Expect(fs.Chmod("/d1/d2/f2", 0o600)).To(Succeed())
+		Expect(fs.Symlink("d1/f1", "/d1/link")).To(Succeed())
+		digest, err = TreeDigest(fs, "/d1", nil)
+		Expect(err).To(Succeed())
+		Expect(digest).To(HaveLen(32))
+	})
+
+	It("calculates file digests", func() {
+		Expect(FileDigest(fs, "/d1/f1", nil)).To(HaveLen(32))
+		Expect(FileDigest(fs, "/d1/f1", sha512.New)).To(HaveLen(64))
+	})
+
+	It("is independent of the filesystem implementation", func() {
+		yfs, err := yamlfs.New(nil)
+		Expect(err).To(Succeed())
+		Expect(CopyDir(fs, "/d1", yfs, "/d1")).To(Succeed())
+		Expect(TreeDigest(yfs, "/d1", nil)).To(Equal(digest))
+
+		tmp, err := osfs.NewTempFileSystem()
+		Expect(err).To(Succeed())
+		defer Cleanup(tmp)
+		Expect(CopyDir(fs, "/d1", tmp, "/d1")).To(Succeed())
+		Expect(tmp.Chmod("/d1", 0o755)).To(Succeed())
+		Expect(TreeDigest(tmp, "/d1", nil)).To(Equal(digest))
+	})
+
+	It("covers content, names, modes and link targets", func() {
+		other := memoryfs.New()
+		check := func(modify func()) {
+			ExpectWithOffset(1, CopyDir(fs, "/d1", other, "/d1")).To(Succeed())
+			d, err := TreeDigest(other, "/d1", nil)
+			ExpectWithOffset(1, err).To(Succeed())
+			ExpectWithOffset(1, d).To(Equal(digest))
+			modify()
+			d, err = TreeDigest(other, "/d1", nil)
+			ExpectWithOffset(1, err).To(Succeed())
+			ExpectWithOffset(1, d).NotTo(Equal(digest))
+			ExpectWithOffset(1, other.RemoveAll("/d1")).To(Succeed())
+		}
+		check(func() { ExpectFileCreate(other, "/d1/d2/f2", []byte("changed"), nil) })
+		check(func() { Expect(other.Rename("/d1/d2/f2", "/d1/d2/f3")).To(Succeed()) })
+		check(func() { Expect(other.Chmod("/d1/f1", 0o600)).To(Succeed()) })
+		check(func() { Expect(other.Chmod("/d1/d2", 0o700)).To(Succeed()) })
+		check(func() {
+			Expect(other.Remove("/d1/link")).To(Succeed())
+			Expect(other.Symlink("d1/d2", "/d1/link")).To(Succeed())
+		})
+	})
+
+	It("supports other hashes", func() {
+		d, err := TreeDigest(fs, "/d1", &DigestOptions{Hash: sha512.New})
+		Expect(err).To(Succeed())
+		Expect(d).To(HaveLen(64))
+	})
+
+	It("calculates digests in parallel", func() {
+		for i := 0; i < 20; i++ {
+			ExpectFileCreate(fs, Join(fs, "/d1/d2", string(rune('a'+i))), []byte{byte(i)}, nil)
+		}
+		d, err := TreeDigest(fs, "/d1", nil)
+		Expect(err).To(Succeed())
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Workers: 4})).To(Equal(d))
+	})
+
+	It("caches file digests", func() {
+		cache := NewDigestCache()
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Cache: cache})).To(Equal(digest))
+		Expect(cache.Len()).To(Equal(2))
+
+		fi, err := fs.Stat("/d1/f1")
+		Expect(err).To(Succeed())
+		mtime := fi.ModTime()
+		ExpectFileCreate(fs, "/d1/f1", []byte("CONTENT"), nil)
+		Expect(fs.Chtimes("/d1/f1", mtime, mtime)).To(Succeed())
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Cache: cache})).To(Equal(digest))
+		Expect(TreeDigest(fs, "/d1", nil)).NotTo(Equal(digest))
+
+		Expect(fs.Chtimes("/d1/f1", mtime, mtime.Add(1))).To(Succeed())
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Cache: cache})).NotTo(Equal(digest))
+	})
+
+	It("separates cached digests of different hash functions", func() {
+		cache := NewDigestCache()
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Cache: cache})).To(Equal(digest))
+
+		d224, err := TreeDigest(fs, "/d1", &DigestOptions{Hash: sha256.New224})
+		Expect(err).To(Succeed())
+		Expect(TreeDigest(fs, "/d1", &DigestOptions{Hash: sha256.New224, Algorithm: "sha224", Cache: cache})).To(Equal(d224))
+		Expect(cache.Len()).To(Equal(4))
+
+		_, err = TreeDigest(fs, "/d1", &DigestOptions{Hash: sha256.New224, Cache: cache})
+		Expect(err).To(MatchError(ContainSubstring("algorithm name")))
+	})
+
+	It("rejects missing entries", func() {
+		_, err := TreeDigest(fs, "/missing", nil)
+		Expect(IsErrNotExist(err)).To(BeTrue())
+	})
+
+})
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"os"
 	"reflect"
+	"syscall"
 )
 
 type ErrorMatcher func(err error) bool
@@ -89,14 +90,63 @@ func IsErrReadOnly(err error) bool {
 	return MatchErr(err, nil, ErrReadOnly)
 }
 
+func IsErrNotSupported(err error) bool {
+	return MatchErr(err, isUnderlyingErrNotSupported, ErrNotSupported)
+}
+
+func isUnderlyingErrNotSupported(err error) bool {
+	return errors.Is(err, ErrNotSupported)
+}
+
+func IsErrCrossDevice(err error) bool {
+	return MatchErr(err, isUnderlyingErrCrossDevice, ErrCrossDevice)
+}
+
+func isUnderlyingErrCrossDevice(err error) bool {
+	return err == syscall.EXDEV
+}
+
+func IsErrBusy(err error) bool {
+	return MatchErr(err, isUnderlyingErrBusy, ErrBusy)
+}
+
+func isUnderlyingErrBusy(err error) bool {
+	return err == syscall.EBUSY
+}
+
+func IsErrWouldBlock(err error) bool {
+	return MatchErr(err, isUnderlyingErrWouldBlock, ErrWouldBlock)
+}
+
+func isUnderlyingErrWouldBlock(err error) bool {
+	return err == syscall.EWOULDBLOCK || err == syscall.EAGAIN
+}
+
+func IsErrNoAttr(err error) bool {
+	return MatchErr(err, isUnderlyingErrNoAttr, ErrNoAttr)
+}
+
 func NewPathError(op string, path string, err error) error {
 	return &os.PathError{Op: op, Path: path, Err: err}
 }
 
 var ErrNotDir = errors.New("is no directory")
+var ErrNotFile = errors.New("is no regular file")
 var ErrNotExist = os.ErrNotExist
 var ErrPermission = os.ErrPermission
 var ErrExist = os.ErrExist
 
+var ErrNotSupported = errors.ErrUnsupported
+
+var ErrCrossDevice = errors.New("invalid cross-device link")
+
+var ErrBusy = errors.New("device or resource busy")
+
+var ErrWouldBlock = errors.New("lock held by another file")
+
+var ErrSymlinkLoop = errors.New("symbolic link loop")
+
+var ErrNoAttr = errors.New("no such attribute")
+
 var ErrReadOnly = errors.New("filehandle is not writable")
 var ErrNotEmpty = errors.New("dir not empty")
@@ -0,0 +1,335 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// readLinkFS is the interface of io/fs.ReadLinkFS (introduced with Go 1.25)
+// used to access symbolic links of an io/fs.FS.
+type readLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+type ioFileSystem struct {
+	fsys     fs.FS
+	readlink readLinkFS
+}
+
+var _ FileSystem = (*ioFileSystem)(nil)
+
+// FromIoFS provides a read-only virtual filesystem for an io/fs.FS, for
+// example an embed.FS, a zip.Reader or a testing/fstest.MapFS.
+// The optional interfaces fs.StatFS and fs.ReadDirFS are used, if
+// available. Symbolic links are supported, if the filesystem provides
+// the methods ReadLink and Lstat (see fs.ReadLinkFS).
+// All modifying operations fail with ErrReadOnly.
+func FromIoFS(fsys fs.FS) FileSystem {
+	r, _ := fsys.(readLinkFS)
+	return &ioFileSystem{fsys: fsys, readlink: r}
+}
+
+func (i *ioFileSystem) Name() string {
+	return "IoFileSystem"
+}
+
+func (i *ioFileSystem) VolumeName(name string) string {
+	return ""
+}
+
+func (i *ioFileSystem) FSTempDir() string {
+	return PathSeparatorString
+}
+
+func (i *ioFileSystem) Normalize(path string) string {
+	return path
+}
+
+func (i *ioFileSystem) Getwd() (string, error) {
+	return PathSeparatorString, nil
+}
+
+// resolve maps a path of the virtual filesystem to the
+// name used for the io/fs.FS. Symbolic links are evaluated,
+// if supported. A final symbolic link is only followed, if link
+// is true.
+func (i *ioFileSystem) resolve(op, name string, link bool) (string, error) {
+	_, elems, _ := SplitPath(i, name)
+	resolved := []string{}
+	links := 0
+	for len(elems) > 0 {
+		e := elems[0]
+		elems = elems[1:]
+		switch e {
+		case ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+		if i.readlink == nil || (len(elems) == 0 && !link) {
+			resolved = append(resolved, e)
+			continue
+		}
+		cur := strings.Join(append(resolved, e), PathSeparatorString)
+		fi, err := i.readlink.Lstat(cur)
+		if err != nil {
+			if len(elems) == 0 && IsErrNotExist(err) {
+				resolved = append(resolved, e)
+				continue
+			}
+			return "", NewPathError(op, name, err)
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = append(resolved, e)
+			continue
+		}
+		links++
+		if links > 255 {
+			return "", NewPathError(op, name, errors.New("too many links"))
+		}
+		target, err := i.readlink.ReadLink(cur)
+		if err != nil {
+			return "", NewPathError(op, name, err)
+		}
+		_, nested, rooted := SplitPath(i, target)
+		if rooted {
+			resolved = resolved[:0]
+		}
+		elems = append(nested, elems...)
+	}
+	if len(resolved) == 0 {
+		return ".", nil
+	}
+	return strings.Join(resolved, PathSeparatorString), nil
+}
+
+func (i *ioFileSystem) Open(name string) (File, error) {
+	p, err := i.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := i.fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	return &ioFile{fsys: i.fsys, path: p, name: name, file: f}, nil
+}
+
+func (i *ioFileSystem) OpenFile(name string, flags int, perm FileMode) (File, error) {
+	if flags&(O_WRONLY|O_RDWR|O_CREATE|O_TRUNC|O_APPEND) != 0 {
+		return nil, NewPathError("open", name, ErrReadOnly)
+	}
+	return i.Open(name)
+}
+
+func (i *ioFileSystem) Stat(name string) (FileInfo, error) {
+	p, err := i.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(i.fsys, p)
+}
+
+func (i *ioFileSystem) Lstat(name string) (FileInfo, error) {
+	p, err := i.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	if i.readlink != nil {
+		return i.readlink.Lstat(p)
+	}
+	return fs.Stat(i.fsys, p)
+}
+
+func (i *ioFileSystem) Readlink(name string) (string, error) {
+	if i.readlink == nil {
+		return "", NewPathError("readlink", name, errors.New("no symlink"))
+	}
+	p, err := i.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return i.readlink.ReadLink(p)
+}
+
+func (i *ioFileSystem) Create(name string) (File, error) {
+	return nil, NewPathError("create", name, ErrReadOnly)
+}
+
+func (i *ioFileSystem) Mkdir(name string, perm FileMode) error {
+	return NewPathError("mkdir", name, ErrReadOnly)
+}
+
+func (i *ioFileSystem) MkdirAll(path string, perm FileMode) error {
+	return NewPathError("mkdir", path, ErrReadOnly)
+}
+
+func (i *ioFileSystem) Remove(name string) error {
+	return NewPathError("remove", name, ErrReadOnly)
+}
+
+func (i *ioFileSystem) RemoveAll(path string) error {
+	return NewPathError("removeall", path, ErrReadOnly)
+}
+
+func (i *ioFileSystem) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrReadOnly}
+}
+
+func (i *ioFileSystem) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: ErrReadOnly}
+}
+
+func (i *ioFileSystem) Chmod(name string, mode FileMode) error {
+	return NewPathError("chmod", name, ErrReadOnly)
+}
+
+func (i *ioFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return NewPathError("chtimes", name, ErrReadOnly)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type ioFile struct {
+	fsys    fs.FS
+	path    string
+	name    string
+	file    fs.File
+	entries []DirEntry
+}
+
+var _ File = (*ioFile)(nil)
+
+func (f *ioFile) Name() string {
+	return f.name
+}
+
+func (f *ioFile) Close() error {
+	return f.file.Close()
+}
+
+func (f *ioFile) Read(b []byte) (int, error) {
+	return f.file.Read(b)
+}
+
+func (f *ioFile) ReadAt(b []byte, off int64) (int, error) {
+	if r, ok := f.file.(io.ReaderAt); ok {
+		return r.ReadAt(b, off)
+	}
+	return 0, NewPathError("readat", f.name, ErrNotSupported)
+}
+
+func (f *ioFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.file.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, NewPathError("seek", f.name, ErrNotSupported)
+}
+
+func (f *ioFile) Write(b []byte) (int, error) {
+	return 0, NewPathError("write", f.name, ErrReadOnly)
+}
+
+func (f *ioFile) WriteAt(b []byte, off int64) (int, error) {
+	return 0, NewPathError("write", f.name, ErrReadOnly)
+}
+
+func (f *ioFile) WriteString(s string) (int, error) {
+	return 0, NewPathError("write", f.name, ErrReadOnly)
+}
+
+func (f *ioFile) Truncate(size int64) error {
+	return NewPathError("truncate", f.name, ErrReadOnly)
+}
+
+func (f *ioFile) Sync() error {
+	return nil
+}
+
+func (f *ioFile) Stat() (FileInfo, error) {
+	return f.file.Stat()
+}
+
+// ReadDir uses fs.ReadDir for the first call, which
+// prefers an fs.ReadDirFS implementation of the filesystem.
+func (f *ioFile) ReadDir(count int) ([]DirEntry, error) {
+	if f.entries == nil {
+		fi, err := f.file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, NewPathError("readdir", f.name, ErrNotDir)
+		}
+		f.entries, err = fs.ReadDir(f.fsys, f.path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if count <= 0 {
+		list := f.entries
+		f.entries = f.entries[len(f.entries):]
+		return list, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	list := f.entries[:count]
+	f.entries = f.entries[count:]
+	return list, nil
+}
+
+func (f *ioFile) Readdir(count int) ([]FileInfo, error) {
+	entries, err := f.ReadDir(count)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return list, err
+		}
+		list = append(list, fi)
+	}
+	return list, nil
+}
+
+func (f *ioFile) Readdirnames(count int) ([]string, error) {
+	entries, err := f.ReadDir(count)
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, err
+}
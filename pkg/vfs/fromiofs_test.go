@@ -0,0 +1,164 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	iofs "io/fs"
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("io/fs adapter", func() {
+	var mapfs fstest.MapFS
+	var fs FileSystem
+
+	BeforeEach(func() {
+		mapfs = fstest.MapFS{
+			"d1/f1":    &fstest.MapFile{Data: []byte("file1"), Mode: 0o644},
+			"d1/f2":    &fstest.MapFile{Data: []byte("file2"), Mode: 0o600},
+			"d1/d2/f3": &fstest.MapFile{Data: []byte("file3"), Mode: 0o644},
+			"f4":       &fstest.MapFile{Data: []byte("file4"), Mode: 0o644},
+		}
+		fs = FromIoFS(mapfs)
+	})
+
+	It("reads files", func() {
+		ExpectFileContent(fs, "/d1/f1", "file1")
+		ExpectFileContent(fs, "d1/d2/f3", "file3")
+		ExpectFileContent(fs, "/d1/d2/../../f4", "file4")
+	})
+
+	It("stats files", func() {
+		fi, err := fs.Stat("/d1/f2")
+		Expect(err).To(Succeed())
+		Expect(fi.Name()).To(Equal("f2"))
+		Expect(fi.Size()).To(Equal(int64(5)))
+		Expect(fi.Mode()).To(Equal(os.FileMode(0o600)))
+
+		fi, err = fs.Stat("/")
+		Expect(err).To(Succeed())
+		Expect(fi.IsDir()).To(BeTrue())
+
+		_, err = fs.Stat("/d1/unknown")
+		Expect(IsErrNotExist(err)).To(BeTrue())
+	})
+
+	It("reads directories", func() {
+		ExpectFolders(fs, "/", []string{"d1", "f4"}, nil)
+		ExpectFolders(fs, "/d1", []string{"d2", "f1", "f2"}, nil)
+
+		f, err := fs.Open("/d1")
+		Expect(err).To(Succeed())
+		defer f.Close()
+		names, err := f.Readdirnames(2)
+		Expect(err).To(Succeed())
+		Expect(names).To(Equal([]string{"d2", "f1"}))
+		names, err = f.Readdirnames(2)
+		Expect(err).To(Succeed())
+		Expect(names).To(Equal([]string{"f2"}))
+		_, err = f.Readdirnames(2)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("walks", func() {
+		var paths []string
+		Expect(Walk(fs, "/", func(path string, info FileInfo, err error) error {
+			paths = append(paths, path)
+			return err
+		})).To(Succeed())
+		Expect(paths).To(Equal([]string{"/", "/d1", "/d1/d2", "/d1/d2/f3", "/d1/f1", "/d1/f2", "/f4"}))
+	})
+
+	It("rejects modifications", func() {
+		_, err := fs.Create("/f5")
+		Expect(IsErrReadOnly(err)).To(BeTrue())
+		_, err = fs.OpenFile("/f4", os.O_RDWR, 0)
+		Expect(IsErrReadOnly(err)).To(BeTrue())
+		Expect(IsErrReadOnly(fs.Mkdir("/d3", 0o755))).To(BeTrue())
+		Expect(IsErrReadOnly(fs.Remove("/f4"))).To(BeTrue())
+		Expect(IsErrReadOnly(fs.RemoveAll("/d1"))).To(BeTrue())
+		Expect(IsErrReadOnly(fs.Rename("/f4", "/f5"))).To(BeTrue())
+		Expect(IsErrReadOnly(fs.Symlink("f4", "/f5"))).To(BeTrue())
+		Expect(IsErrReadOnly(fs.Chmod("/f4", 0o600))).To(BeTrue())
+
+		f, err := fs.Open("/f4")
+		Expect(err).To(Succeed())
+		defer f.Close()
+		_, err = f.Write([]byte("test"))
+		Expect(IsErrReadOnly(err)).To(BeTrue())
+	})
+
+	It("supports random access", func() {
+		f, err := fs.Open("/f4")
+		Expect(err).To(Succeed())
+		defer f.Close()
+		Expect(f.Seek(2, 0)).To(Equal(int64(2)))
+		ExpectRead(f, []byte("le4"))
+	})
+
+	It("handles filesystems without seek support", func() {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		e, err := w.Create("d1/f1")
+		Expect(err).To(Succeed())
+		_, err = e.Write([]byte("zipped"))
+		Expect(err).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		Expect(err).To(Succeed())
+
+		fs := FromIoFS(r)
+		ExpectFileContent(fs, "/d1/f1", "zipped")
+		ExpectFolders(fs, "/", []string{"d1"}, nil)
+
+		f, err := fs.Open("/d1/f1")
+		Expect(err).To(Succeed())
+		defer f.Close()
+		_, err = f.Seek(2, 0)
+		Expect(IsErrNotSupported(err)).To(BeTrue())
+	})
+
+	It("handles symbolic links", func() {
+		if _, ok := iofs.FS(mapfs).(interface{ ReadLink(string) (string, error) }); !ok {
+			Skip("symbolic links not supported by testing/fstest")
+		}
+		mapfs["link"] = &fstest.MapFile{Data: []byte("d1/d2"), Mode: os.ModeSymlink | 0o777}
+		mapfs["d1/abs"] = &fstest.MapFile{Data: []byte("/f4"), Mode: os.ModeSymlink | 0o777}
+		ExpectFileContent(fs, "/link/f3", "file3")
+		ExpectFileContent(fs, "/link/../f1", "file1")
+		ExpectFileContent(fs, "/d1/abs", "file4")
+
+		fi, err := fs.Lstat("/link")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode() & os.ModeSymlink).NotTo(BeZero())
+		Expect(fs.Readlink("/link")).To(Equal("d1/d2"))
+
+		fi, err = fs.Stat("/link")
+		Expect(err).To(Succeed())
+		Expect(fi.IsDir()).To(BeTrue())
+	})
+})
@@ -0,0 +1,296 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Match reports whether name matches the shell pattern.
+// It supports the syntax of path.Match (with the path separator /)
+// extended by brace expansion ({a,b}) and the path segment **,
+// which matches zero or more path segments.
+// The only possible returned error is path.ErrBadPattern.
+func Match(pattern, name string) (bool, error) {
+	names := strings.Split(name, "/")
+	for _, p := range expandBraces(pattern) {
+		if err := validatePattern(p); err != nil {
+			return false, err
+		}
+		ok, err := matchSegments(strings.Split(p, "/"), names)
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// MatchList matches a name against a list of patterns (see Match).
+// Patterns starting with ! are negated: a later matching negated pattern
+// excludes a name matched by an earlier pattern, like for .gitignore files.
+func MatchList(patterns []string, name string) (bool, error) {
+	matched := false
+	for _, p := range patterns {
+		neg := strings.HasPrefix(p, "!")
+		if neg {
+			p = p[1:]
+		}
+		ok, err := Match(p, name)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched = !neg
+		}
+	}
+	return matched, nil
+}
+
+// Glob returns the names of all files matching the pattern (see Match)
+// or nil if there is no matching file. The result is sorted.
+// Like for EvalSymlinks, symbolic links are followed for all
+// path segments but the last one. To avoid cycles, symbolic links
+// to directories are not followed for **.
+// The only possible returned error is path.ErrBadPattern.
+func Glob(fs FileSystem, pattern string) ([]string, error) {
+	var result []string
+	found := map[string]struct{}{}
+	for _, p := range expandBraces(pattern) {
+		if err := validatePattern(p); err != nil {
+			return nil, err
+		}
+		vol, elems, rooted := SplitPath(fs, p)
+		base := vol
+		if rooted {
+			base += PathSeparatorString
+		}
+		for _, m := range glob(fs, base, elems, nil) {
+			if _, ok := found[m]; !ok {
+				found[m] = struct{}{}
+				result = append(result, m)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// GlobList returns the names of all files matching the list of
+// patterns with the semantics of MatchList. Negated patterns exclude
+// files matched by earlier patterns.
+func GlobList(fs FileSystem, patterns ...string) ([]string, error) {
+	var result []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			var n []string
+			for _, e := range result {
+				ok, err := Match(p[1:], e)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					n = append(n, e)
+				}
+			}
+			result = n
+			continue
+		}
+		list, err := Glob(fs, p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, list...)
+	}
+	sort.Strings(result)
+	n := result[:0]
+	for i, e := range result {
+		if i == 0 || e != result[i-1] {
+			n = append(n, e)
+		}
+	}
+	return n, nil
+}
+
+func glob(fs FileSystem, base string, elems []string, result []string) []string {
+	if len(elems) == 0 {
+		if base == "" {
+			return result
+		}
+		if _, err := fs.Lstat(base); err == nil {
+			result = append(result, base)
+		}
+		return result
+	}
+
+	p := elems[0]
+	if p == "**" {
+		result = glob(fs, base, elems[1:], result)
+		for _, n := range globDirNames(fs, base) {
+			name := globJoin(fs, base, n)
+			fi, err := fs.Lstat(name)
+			switch {
+			case err != nil:
+			case fi.IsDir():
+				result = glob(fs, name, elems, result)
+			case len(elems) == 1:
+				// a trailing ** matches all entries
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+	if !hasMeta(p) {
+		p = unescape(p)
+		name := globJoin(fs, base, p)
+		if len(elems) > 1 {
+			if fi, err := fs.Stat(name); err != nil || !fi.IsDir() {
+				return result
+			}
+		}
+		return glob(fs, name, elems[1:], result)
+	}
+	for _, n := range globDirNames(fs, base) {
+		if ok, _ := path.Match(p, n); !ok {
+			continue
+		}
+		name := globJoin(fs, base, n)
+		if len(elems) > 1 {
+			if fi, err := fs.Stat(name); err != nil || !fi.IsDir() {
+				continue
+			}
+		}
+		result = glob(fs, name, elems[1:], result)
+	}
+	return result
+}
+
+func globDirNames(fs FileSystem, dir string) []string {
+	if dir == "" {
+		dir = "."
+	}
+	names, err := readDirNames(fs, dir)
+	if err != nil {
+		return nil
+	}
+	return names
+}
+
+func globJoin(fs FileSystem, base, name string) string {
+	if base == "" {
+		return name
+	}
+	return Join(fs, base, name)
+}
+
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+func unescape(p string) string {
+	if !strings.Contains(p, `\`) {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+1 < len(p) {
+			i++
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+func validatePattern(pattern string) error {
+	for _, p := range strings.Split(pattern, "/") {
+		if _, err := path.Match(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchSegments(patterns, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		p := patterns[0]
+		if p == "**" {
+			for i := 0; i <= len(names); i++ {
+				ok, err := matchSegments(patterns[1:], names[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(names) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(p, names[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0, nil
+}
+
+// expandBraces expands all (possibly nested) brace
+// expressions {a,b} of a pattern.
+func expandBraces(pattern string) []string {
+	start := -1
+	depth := 0
+	var alts []string
+	last := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+				last = i + 1
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				alts = append(alts, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				alts = append(alts, pattern[last:i])
+				var result []string
+				for _, suffix := range expandBraces(pattern[i+1:]) {
+					for _, a := range alts {
+						for _, e := range expandBraces(a) {
+							result = append(result, pattern[:start]+e+suffix)
+						}
+					}
+				}
+				return result
+			}
+		}
+	}
+	return []string{pattern}
+}
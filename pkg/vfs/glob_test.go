@@ -0,0 +1,112 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("glob", func() {
+	var fs VFS
+
+	BeforeEach(func() {
+		fs = New(memoryfs.New())
+		Expect(fs.MkdirAll("/d1/d2/d3", os.ModePerm)).To(Succeed())
+		Expect(fs.MkdirAll("/d4", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(fs, "/d1/a.go", nil, nil)
+		ExpectFileCreate(fs, "/d1/b.txt", nil, nil)
+		ExpectFileCreate(fs, "/d1/d2/c.go", nil, nil)
+		ExpectFileCreate(fs, "/d1/d2/d3/d.go", nil, nil)
+		ExpectFileCreate(fs, "/d4/e.go", nil, nil)
+		Expect(fs.Symlink("/d1/d2", "/link")).To(Succeed())
+	})
+
+	Context("match", func() {
+		It("matches like path.Match", func() {
+			Expect(Match("d1/*.go", "d1/a.go")).To(BeTrue())
+			Expect(Match("*.go", "d1/a.go")).To(BeFalse())
+			Expect(Match("d?/[ab].go", "d1/b.go")).To(BeTrue())
+		})
+		It("matches recursively", func() {
+			Expect(Match("**/*.go", "a.go")).To(BeTrue())
+			Expect(Match("**/*.go", "d1/d2/a.go")).To(BeTrue())
+			Expect(Match("d1/**/d3", "d1/d3")).To(BeTrue())
+			Expect(Match("d1/**/d3", "d1/d2/d3")).To(BeTrue())
+			Expect(Match("d1/**", "d1/d2/d3")).To(BeTrue())
+			Expect(Match("d1/**", "d1/a.go")).To(BeTrue())
+			Expect(Match("d1/**/d3", "d2/d3")).To(BeFalse())
+		})
+		It("expands braces", func() {
+			Expect(Match("*.{go,txt}", "a.txt")).To(BeTrue())
+			Expect(Match("{d1,d{2,3}}/a", "d3/a")).To(BeTrue())
+			Expect(Match("{d1,d{2,3}}/a", "d4/a")).To(BeFalse())
+			Expect(Match(`\{a,b}`, "{a,b}")).To(BeTrue())
+		})
+		It("rejects bad patterns", func() {
+			_, err := Match("[", "a")
+			Expect(err).To(Equal(path.ErrBadPattern))
+		})
+		It("matches lists with negation", func() {
+			list := []string{"**/*.go", "!**/d3/*", "d1/d2/d3/d.go"}
+			Expect(MatchList(list, "d1/a.go")).To(BeTrue())
+			Expect(MatchList(list, "d1/d3/a.go")).To(BeFalse())
+			Expect(MatchList(list, "d1/d2/d3/d.go")).To(BeTrue())
+		})
+	})
+
+	Context("glob", func() {
+		It("globs like filepath.Glob", func() {
+			Expect(fs.Glob("/d1/*.go")).To(Equal([]string{"/d1/a.go"}))
+			Expect(fs.Glob("/d?/*")).To(Equal([]string{"/d1/a.go", "/d1/b.txt", "/d1/d2", "/d4/e.go"}))
+			Expect(fs.Glob("/d1/x*")).To(BeNil())
+		})
+		It("globs recursively", func() {
+			Expect(fs.Glob("/**/*.go")).To(Equal([]string{"/d1/a.go", "/d1/d2/c.go", "/d1/d2/d3/d.go", "/d4/e.go"}))
+			Expect(fs.Glob("/d1/**/d3")).To(Equal([]string{"/d1/d2/d3"}))
+			Expect(fs.Glob("/d1/**")).To(Equal([]string{"/d1", "/d1/a.go", "/d1/b.txt", "/d1/d2", "/d1/d2/c.go", "/d1/d2/d3", "/d1/d2/d3/d.go"}))
+			Expect(fs.Glob("/**")).To(ContainElement("/link"))
+		})
+		It("globs with braces", func() {
+			Expect(fs.Glob("/{d1,d4}/*.{go,txt}")).To(Equal([]string{"/d1/a.go", "/d1/b.txt", "/d4/e.go"}))
+		})
+		It("globs relative paths", func() {
+			Expect(fs.Glob("d1/*/c.go")).To(Equal([]string{"d1/d2/c.go"}))
+		})
+		It("follows symbolic links", func() {
+			Expect(fs.Glob("/link/*.go")).To(Equal([]string{"/link/c.go"}))
+			Expect(fs.Glob("/l*")).To(Equal([]string{"/link"}))
+			Expect(fs.Glob("/**/d.go")).To(Equal([]string{"/d1/d2/d3/d.go"}))
+		})
+		It("globs lists with negation", func() {
+			Expect(GlobList(fs, "/**/*.go", "!/d1/**", "/d1/a.go")).To(Equal([]string{"/d1/a.go", "/d4/e.go"}))
+		})
+		It("rejects bad patterns", func() {
+			_, err := fs.Glob("/d1/[")
+			Expect(err).To(Equal(path.ErrBadPattern))
+		})
+	})
+})
@@ -19,6 +19,7 @@
 package vfs
 
 import (
+	"context"
 	"io"
 	"io/fs"
 	"os"
@@ -137,6 +138,69 @@ type FileSystemWithWorkingDirectory interface {
 	Chdir(path string) error
 }
 
+// OwnershipFileSystem is an optional interface for filesystems
+// supporting the ownership of files.
+// The ownership of a file is exposed by the value returned by
+// FileInfo.Sys() (see Owner).
+type OwnershipFileSystem interface {
+	FileSystem
+
+	// Chown changes the numeric uid and gid of the named file.
+	// If the file is a symbolic link, it changes the uid and gid
+	// of the link's target. A uid or gid of -1 means to not change
+	// that value.
+	Chown(name string, uid, gid int) error
+
+	// Lchown changes the numeric uid and gid of the named file.
+	// If the file is a symbolic link, it changes the uid and gid
+	// of the link itself.
+	Lchown(name string, uid, gid int) error
+}
+
+// LinkFileSystem is an optional interface for filesystems
+// supporting hard links.
+// The number of links of a file is exposed by the value returned by
+// FileInfo.Sys() (see Nlink).
+type LinkFileSystem interface {
+	FileSystem
+
+	// Link creates newname as a hard link to the oldname file.
+	// Hard links to directories are not supported.
+	Link(oldname, newname string) error
+}
+
+// Flags for the Setxattr and Lsetxattr operations.
+const (
+	// XATTR_CREATE fails the operation, if the attribute already exists.
+	XATTR_CREATE = 0x1
+	// XATTR_REPLACE fails the operation, if the attribute does not exist.
+	XATTR_REPLACE = 0x2
+)
+
+// XattrFileSystem is an optional interface for filesystems
+// supporting extended attributes.
+// The L-variants of the operations do not follow a symbolic link
+// but work on the link itself.
+type XattrFileSystem interface {
+	FileSystem
+
+	// Getxattr returns the value of the extended attribute attr of the named file.
+	Getxattr(name, attr string) ([]byte, error)
+	// Setxattr sets the value of the extended attribute attr of the named file.
+	// The flags XATTR_CREATE and XATTR_REPLACE can be used to
+	// require the absence or presence of the attribute.
+	Setxattr(name, attr string, data []byte, flags int) error
+	// Listxattr returns the names of all extended attributes of the named file.
+	Listxattr(name string) ([]string, error)
+	// Removexattr removes the extended attribute attr of the named file.
+	Removexattr(name, attr string) error
+
+	Lgetxattr(name, attr string) ([]byte, error)
+	Lsetxattr(name, attr string, data []byte, flags int) error
+	Llistxattr(name string) ([]string, error)
+	Lremovexattr(name, attr string) error
+}
+
 type FileSystemCleanup interface {
 	FileSystem
 
@@ -185,6 +249,7 @@ type VFS interface {
 	Rel(src, tgt string) (string, error)
 	EvalSymlinks(path string) (string, error)
 	Walk(path string, fn WalkFunc) error
+	Glob(pattern string) ([]string, error)
 
 	Exists(path string) (bool, error)
 	FileExists(path string) (bool, error)
@@ -196,8 +261,35 @@ type VFS interface {
 	ReadDir(path string) ([]FileInfo, error)
 	ReadFile(path string) ([]byte, error)
 	WriteFile(path string, data []byte, mode FileMode) error
+	WriteFileAtomic(path string, data []byte, mode FileMode) error
 	TempFile(dir, prefix string) (File, error)
 	TempDir(dir, prefix string) (string, error)
+
+	Chown(name string, uid, gid int) error
+	Lchown(name string, uid, gid int) error
+	Link(oldname, newname string) error
+	Watch(path string, recursive bool) (Watcher, error)
+
+	Getxattr(name, attr string) ([]byte, error)
+	Setxattr(name, attr string, data []byte, flags int) error
+	Listxattr(name string) ([]string, error)
+	Removexattr(name, attr string) error
+	Lgetxattr(name, attr string) ([]byte, error)
+	Lsetxattr(name, attr string, data []byte, flags int) error
+	Llistxattr(name string) ([]string, error)
+	Lremovexattr(name, attr string) error
+
+	CreateContext(ctx context.Context, name string) (File, error)
+	OpenContext(ctx context.Context, name string) (File, error)
+	OpenFileContext(ctx context.Context, name string, flags int, perm FileMode) (File, error)
+	StatContext(ctx context.Context, name string) (FileInfo, error)
+	LstatContext(ctx context.Context, name string) (FileInfo, error)
+	ReadDirContext(ctx context.Context, name string) ([]DirEntry, error)
+	MkdirContext(ctx context.Context, name string, perm FileMode) error
+	MkdirAllContext(ctx context.Context, path string, perm FileMode) error
+	RemoveContext(ctx context.Context, name string) error
+	RemoveAllContext(ctx context.Context, path string) error
+	RenameContext(ctx context.Context, oldname, newname string) error
 }
 
 func Cleanup(fs FileSystem) error {
@@ -20,24 +20,71 @@ package vfs
 
 import (
 	"io/fs"
+	"path"
 	"sort"
 )
 
+// IoFS is the complete io/fs view of a virtual filesystem.
+// Besides the standard interfaces it provides the methods
+// ReadLink and Lstat (see io/fs.ReadLinkFS).
+type IoFS interface {
+	fs.ReadDirFS
+	fs.StatFS
+	fs.ReadFileFS
+	fs.GlobFS
+	fs.SubFS
+
+	ReadLink(name string) (string, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
 type iofs struct {
 	FileSystem
+	dir string
 }
 
 var (
 	_ fs.File        = File(nil)
 	_ fs.ReadDirFile = File(nil)
+
+	_ IoFS = (*iofs)(nil)
 )
 
+// AsIoFS maps a virtual filesystem to an io/fs filesystem.
+// Names are validated according to fs.ValidPath and
+// interpreted relative to the current working directory
+// of the virtual filesystem.
+func AsIoFS(fs FileSystem) IoFS {
+	return &iofs{FileSystem: fs, dir: "."}
+}
+
+// path validates an io/fs name and maps it to the
+// path used for the virtual filesystem.
+func (i *iofs) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(i.dir, name), nil
+}
+
 func (i *iofs) Open(name string) (fs.File, error) {
-	return i.FileSystem.Open(name)
+	p, err := i.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := i.FileSystem.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
 func (i *iofs) ReadDir(name string) ([]fs.DirEntry, error) {
-	f, err := i.FileSystem.Open(name)
+	p, err := i.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := i.FileSystem.Open(p)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +94,53 @@ func (i *iofs) ReadDir(name string) ([]fs.DirEntry, error) {
 	return dirs, err
 }
 
-// AsIoFS maps a virtual filesystem
-func AsIoFS(fs FileSystem) fs.ReadDirFS {
-	return &iofs{fs}
+func (i *iofs) Stat(name string) (fs.FileInfo, error) {
+	p, err := i.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return i.FileSystem.Stat(p)
+}
+
+func (i *iofs) Lstat(name string) (fs.FileInfo, error) {
+	p, err := i.path("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return i.FileSystem.Lstat(p)
+}
+
+func (i *iofs) ReadLink(name string) (string, error) {
+	p, err := i.path("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return i.FileSystem.Readlink(p)
+}
+
+func (i *iofs) ReadFile(name string) ([]byte, error) {
+	p, err := i.path("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFile(i.FileSystem, p)
+}
+
+func (i *iofs) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	// hide the GlobFS implementation to use the generic algorithm
+	return fs.Glob(struct{ fs.ReadDirFS }{i}, pattern)
+}
+
+func (i *iofs) Sub(dir string) (fs.FS, error) {
+	p, err := i.path("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "." {
+		return i, nil
+	}
+	return &iofs{FileSystem: i.FileSystem, dir: p}, nil
 }
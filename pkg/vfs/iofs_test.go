@@ -0,0 +1,153 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	iofs "io/fs"
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/composefs"
+	"github.com/mandelsoft/vfs/pkg/cwdfs"
+	"github.com/mandelsoft/vfs/pkg/layerfs"
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/osfs"
+	"github.com/mandelsoft/vfs/pkg/projectionfs"
+	"github.com/mandelsoft/vfs/pkg/readonlyfs"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+	"github.com/mandelsoft/vfs/pkg/yamlfs"
+)
+
+var ioFSFiles = []string{"d1/f1", "d1/d2/f2", "f3"}
+
+func populate(fs FileSystem) FileSystem {
+	Expect(fs.MkdirAll("d1/d2", os.ModePerm)).To(Succeed())
+	for _, f := range ioFSFiles {
+		Expect(WriteFile(fs, f, []byte("content of "+f), 0o644)).To(Succeed())
+	}
+	Expect(fs.Symlink("f1", "d1/link")).To(Succeed())
+	return fs
+}
+
+var _ = Describe("io/fs view", func() {
+	var fs FileSystem
+
+	BeforeEach(func() {
+		fs = populate(memoryfs.New())
+	})
+
+	It("rejects invalid names", func() {
+		ifs := AsIoFS(fs)
+		for _, n := range []string{"/f3", "d1/../f3", "./f3", "d1/", ""} {
+			_, err := ifs.Open(n)
+			Expect(err).To(MatchError(os.ErrInvalid), n)
+			_, err = ifs.Stat(n)
+			Expect(err).To(MatchError(os.ErrInvalid), n)
+		}
+	})
+
+	It("reads files and links", func() {
+		Expect(fs.Symlink("d1/f1", "link")).To(Succeed())
+		ifs := AsIoFS(fs)
+		Expect(ifs.ReadFile("link")).To(Equal([]byte("content of d1/f1")))
+		Expect(ifs.ReadLink("link")).To(Equal("d1/f1"))
+		fi, err := ifs.Lstat("link")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode() & os.ModeSymlink).NotTo(BeZero())
+	})
+
+	It("globs", func() {
+		Expect(AsIoFS(fs).Glob("d1/*")).To(Equal([]string{"d1/d2", "d1/f1", "d1/link"}))
+		_, err := AsIoFS(fs).Glob("[")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("provides sub filesystems", func() {
+		sub, err := AsIoFS(fs).Sub("d1")
+		Expect(err).To(Succeed())
+		data, err := sub.(iofs.ReadFileFS).ReadFile("d2/f2")
+		Expect(err).To(Succeed())
+		Expect(string(data)).To(Equal("content of d1/d2/f2"))
+	})
+
+	Context("conformance", func() {
+		check := func(fs FileSystem) {
+			Expect(fstest.TestFS(AsIoFS(fs), ioFSFiles...)).To(Succeed())
+		}
+
+		It("memoryfs", func() {
+			check(fs)
+		})
+
+		It("osfs", func() {
+			tmp, err := osfs.NewTempFileSystem()
+			Expect(err).To(Succeed())
+			defer Cleanup(tmp)
+			check(populate(tmp))
+		})
+
+		It("yamlfs", func() {
+			y, err := yamlfs.New([]byte("{}"))
+			Expect(err).To(Succeed())
+			check(populate(y))
+		})
+
+		It("layerfs", func() {
+			base := memoryfs.New()
+			Expect(base.MkdirAll("d1/d3", os.ModePerm)).To(Succeed())
+			Expect(WriteFile(base, "d1/f1", []byte("base"), 0o644)).To(Succeed())
+			check(populate(layerfs.New(memoryfs.New(), base)))
+		})
+
+		It("composefs", func() {
+			c := composefs.New(memoryfs.New())
+			Expect(c.MkdirAll("mnt", os.ModePerm)).To(Succeed())
+			Expect(c.Mount("mnt", populate(memoryfs.New()))).To(Succeed())
+			check(populate(c))
+		})
+
+		It("readonlyfs", func() {
+			check(readonlyfs.New(fs))
+		})
+
+		It("cwdfs", func() {
+			base := memoryfs.New()
+			Expect(base.MkdirAll("cwd", os.ModePerm)).To(Succeed())
+			populate(base)
+			c, err := cwdfs.New(base, "cwd")
+			Expect(err).To(Succeed())
+			check(populate(c))
+		})
+
+		It("projectionfs", func() {
+			base := memoryfs.New()
+			Expect(base.MkdirAll("root", os.ModePerm)).To(Succeed())
+			p, err := projectionfs.New(base, "root")
+			Expect(err).To(Succeed())
+			check(populate(p))
+		})
+
+		It("io/fs adapter", func() {
+			check(FromIoFS(AsIoFS(fs)))
+		})
+	})
+})
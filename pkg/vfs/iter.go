@@ -0,0 +1,148 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"iter"
+	"os"
+	"sort"
+)
+
+// WalkOptions describes the behaviour of the iterator provided by All.
+type WalkOptions struct {
+	// MaxDepth limits the depth of the visited entries
+	// (the root has depth 0). 0 means unlimited.
+	MaxDepth int
+	// FollowSymlinks follows symbolic links to directories.
+	// Symbolic link loops are reported with ErrSymlinkLoop.
+	FollowSymlinks bool
+	// Unsorted provides the directory entries in the order
+	// of the filesystem instead of lexical order.
+	Unsorted bool
+}
+
+// WalkEntry is an entry of a file tree provided by All.
+type WalkEntry struct {
+	DirEntry
+	// Path is the path of the entry composed of the root
+	// and the path relative to the root.
+	Path string
+	// Depth is the depth of the entry relative to the root.
+	Depth int
+}
+
+// All provides an iterator for the file tree rooted at root,
+// including the root. Errors for an entry (for example a directory
+// not readable) are reported together with the entry, the iteration
+// continues with the next entry. If the root cannot be accessed,
+// only the error is reported.
+// Symbolic links to directories are reported as directories, if
+// they are followed.
+func All(fs FileSystem, root string, opts ...WalkOptions) iter.Seq2[WalkEntry, error] {
+	var o WalkOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return func(yield func(WalkEntry, error) bool) {
+		w := &iterWalker{fs: fs, opts: o, yield: yield, ancestors: map[string]struct{}{}}
+		var fi FileInfo
+		var err error
+		if o.FollowSymlinks {
+			fi, err = fs.Stat(root)
+		} else {
+			fi, err = fs.Lstat(root)
+		}
+		if err != nil {
+			yield(WalkEntry{Path: root}, err)
+			return
+		}
+		w.walk(WalkEntry{DirEntry: dirEntry(fi), Path: root})
+	}
+}
+
+type iterWalker struct {
+	fs        FileSystem
+	opts      WalkOptions
+	yield     func(WalkEntry, error) bool
+	ancestors map[string]struct{}
+}
+
+func (w *iterWalker) walk(e WalkEntry) bool {
+	if !w.yield(e, nil) {
+		return false
+	}
+	if !e.IsDir() || (w.opts.MaxDepth > 0 && e.Depth >= w.opts.MaxDepth) {
+		return true
+	}
+	if w.opts.FollowSymlinks {
+		canon, err := Canonical(w.fs, e.Path, true)
+		if err != nil {
+			return w.yield(e, err)
+		}
+		if _, ok := w.ancestors[canon]; ok {
+			return w.yield(e, NewPathError("walk", e.Path, ErrSymlinkLoop))
+		}
+		w.ancestors[canon] = struct{}{}
+		defer delete(w.ancestors, canon)
+	}
+
+	entries, err := w.readDir(e.Path)
+	if err != nil {
+		return w.yield(e, err)
+	}
+	for _, d := range entries {
+		path := Join(w.fs, e.Path, d.Name())
+		if w.opts.FollowSymlinks && d.Type()&os.ModeSymlink != 0 {
+			if fi, err := w.fs.Stat(path); err == nil {
+				d = &followedEntry{dirEntry(fi), d.Name()}
+			}
+		}
+		if !w.walk(WalkEntry{DirEntry: d, Path: path, Depth: e.Depth + 1}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *iterWalker) readDir(path string) ([]DirEntry, error) {
+	f, err := w.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	list, err := f.ReadDir(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !w.opts.Unsorted {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	}
+	return list, nil
+}
+
+// followedEntry describes the target of a symbolic link
+// under the name of the link.
+type followedEntry struct {
+	DirEntry
+	name string
+}
+
+func (e *followedEntry) Name() string {
+	return e.name
+}
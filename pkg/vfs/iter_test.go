@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("walk dir", func() {
+	var fs VFS
+
+	BeforeEach(func() {
+		fs = New(memoryfs.New())
+		Expect(fs.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		Expect(fs.MkdirAll("/d3", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(fs, "/d1/b", nil, nil)
+		ExpectFileCreate(fs, "/d1/a", nil, nil)
+		ExpectFileCreate(fs, "/d1/d2/c", nil, nil)
+		Expect(fs.Symlink("/d1", "/d3/link")).To(Succeed())
+	})
+
+	Context("WalkDir", func() {
+		It("walks in lexical order", func() {
+			var found []string
+			Expect(WalkDir(fs, "/", func(path string, d DirEntry, err error) error {
+				Expect(err).To(Succeed())
+				found = append(found, fmt.Sprintf("%s %t", path, d.IsDir()))
+				return nil
+			})).To(Succeed())
+			Expect(found).To(Equal([]string{
+				"/ true",
+				"/d1 true",
+				"/d1/a false",
+				"/d1/b false",
+				"/d1/d2 true",
+				"/d1/d2/c false",
+				"/d3 true",
+				"/d3/link false",
+			}))
+		})
+		It("skips directories", func() {
+			var found []string
+			Expect(WalkDir(fs, "/", func(path string, d DirEntry, err error) error {
+				found = append(found, path)
+				if path == "/d1/a" || path == "/d3" {
+					return SkipDir
+				}
+				return nil
+			})).To(Succeed())
+			Expect(found).To(Equal([]string{"/", "/d1", "/d1/a", "/d3"}))
+		})
+		It("skips all", func() {
+			var found []string
+			Expect(WalkDir(fs, "/", func(path string, d DirEntry, err error) error {
+				found = append(found, path)
+				if path == "/d1/d2" {
+					return SkipAll
+				}
+				return nil
+			})).To(Succeed())
+			Expect(found).To(Equal([]string{"/", "/d1", "/d1/a", "/d1/b", "/d1/d2"}))
+		})
+		It("reports missing root", func() {
+			err := WalkDir(fs, "/missing", func(path string, d DirEntry, err error) error {
+				return err
+			})
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("All", func() {
+		It("iterates in lexical order", func() {
+			var found []string
+			for e, err := range All(fs, "/d1") {
+				Expect(err).To(Succeed())
+				found = append(found, fmt.Sprintf("%s %s %d", e.Path, e.Name(), e.Depth))
+			}
+			Expect(found).To(Equal([]string{
+				"/d1 d1 0",
+				"/d1/a a 1",
+				"/d1/b b 1",
+				"/d1/d2 d2 1",
+				"/d1/d2/c c 2",
+			}))
+		})
+		It("stops on break", func() {
+			var found []string
+			for e := range All(fs, "/") {
+				found = append(found, e.Path)
+				if e.Path == "/d1/a" {
+					break
+				}
+			}
+			Expect(found).To(Equal([]string{"/", "/d1", "/d1/a"}))
+		})
+		It("limits depth", func() {
+			var found []string
+			for e, err := range All(fs, "/", WalkOptions{MaxDepth: 1}) {
+				Expect(err).To(Succeed())
+				found = append(found, e.Path)
+			}
+			Expect(found).To(Equal([]string{"/", "/d1", "/d3"}))
+		})
+		It("iterates unsorted", func() {
+			var found []string
+			for e, err := range All(fs, "/d1", WalkOptions{Unsorted: true, MaxDepth: 1}) {
+				Expect(err).To(Succeed())
+				found = append(found, e.Path)
+			}
+			Expect(found).To(ConsistOf("/d1", "/d1/a", "/d1/b", "/d1/d2"))
+		})
+		It("follows symbolic links", func() {
+			var found []string
+			for e, err := range All(fs, "/d3", WalkOptions{FollowSymlinks: true}) {
+				Expect(err).To(Succeed())
+				found = append(found, fmt.Sprintf("%s %s %t", e.Path, e.Name(), e.IsDir()))
+			}
+			Expect(found).To(Equal([]string{
+				"/d3 d3 true",
+				"/d3/link link true",
+				"/d3/link/a a false",
+				"/d3/link/b b false",
+				"/d3/link/d2 d2 true",
+				"/d3/link/d2/c c false",
+			}))
+		})
+		It("detects symbolic link loops", func() {
+			Expect(fs.Symlink("/d1", "/d1/d2/loop")).To(Succeed())
+			var found []string
+			var errs []error
+			for e, err := range All(fs, "/d1", WalkOptions{FollowSymlinks: true}) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				found = append(found, e.Path)
+			}
+			Expect(found).To(Equal([]string{"/d1", "/d1/a", "/d1/b", "/d1/d2", "/d1/d2/c", "/d1/d2/loop"}))
+			Expect(len(errs)).To(Equal(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("symbolic link loop")))
+		})
+		It("reports missing root", func() {
+			n := 0
+			for e, err := range All(fs, "/missing") {
+				Expect(e.Path).To(Equal("/missing"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				n++
+			}
+			Expect(n).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"os"
+)
+
+// LinkInfo is an optional interface for the system specific
+// value returned by FileInfo.Sys() providing the number of
+// hard links of a file.
+type LinkInfo interface {
+	Nlink() uint64
+}
+
+// Nlink returns the number of hard links of the file described
+// by the given FileInfo. If the link count is not available
+// ok is false.
+func Nlink(fi FileInfo) (n uint64, ok bool) {
+	if fi == nil {
+		return 0, false
+	}
+	if l, ok := fi.Sys().(LinkInfo); ok {
+		return l.Nlink(), true
+	}
+	return sysNlink(fi.Sys())
+}
+
+// FileIdentity is an optional interface for the system specific
+// value returned by FileInfo.Sys() to identify the described file.
+type FileIdentity interface {
+	SameFile(sys interface{}) bool
+}
+
+// SameFile reports whether fi1 and fi2 describe the same file,
+// for example two hard links to the same file.
+func SameFile(fi1, fi2 FileInfo) bool {
+	if fi1 == nil || fi2 == nil {
+		return false
+	}
+	if id, ok := fi1.Sys().(FileIdentity); ok {
+		return id.SameFile(fi2.Sys())
+	}
+	return os.SameFile(fi1, fi2)
+}
+
+// Link creates newname as a hard link to the oldname file,
+// if the filesystem supports hard links.
+func Link(fs FileSystem, oldname, newname string) error {
+	if l, ok := fs.(LinkFileSystem); ok {
+		return l.Link(oldname, newname)
+	}
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: ErrNotSupported}
+}
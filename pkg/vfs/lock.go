@@ -0,0 +1,101 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+// LockType describes the kind of an advisory file lock.
+type LockType int
+
+const (
+	// LockShared is a lock, which can be held by multiple
+	// files at the same time.
+	LockShared LockType = iota
+	// LockExclusive is a lock, which can only be held
+	// by a single file.
+	LockExclusive
+)
+
+func (t LockType) String() string {
+	if t == LockExclusive {
+		return "exclusive"
+	}
+	return "shared"
+}
+
+// LockableFile is an optional interface for files
+// supporting advisory locks (like flock).
+// Locks are bound to the file handle and released
+// when it is closed.
+type LockableFile interface {
+	File
+
+	// Lock acquires a lock for the complete file, or converts
+	// an already held lock. If wait is false, it fails with
+	// ErrWouldBlock, if the lock is held by another file.
+	Lock(t LockType, wait bool) error
+	// Unlock releases a lock acquired with Lock.
+	Unlock() error
+}
+
+// RangeLockableFile is an optional interface for files
+// supporting advisory locks for byte ranges (like fcntl).
+type RangeLockableFile interface {
+	LockableFile
+
+	// LockRange acquires a lock for a byte range of the file.
+	// A length of 0 locks the file from offset up to any end.
+	LockRange(offset, length int64, t LockType, wait bool) error
+	// UnlockRange releases a lock acquired with LockRange.
+	UnlockRange(offset, length int64) error
+}
+
+// Lock acquires an advisory lock for a file,
+// if the file supports locking.
+func Lock(f File, t LockType, wait bool) error {
+	if l, ok := f.(LockableFile); ok {
+		return l.Lock(t, wait)
+	}
+	return NewPathError("lock", f.Name(), ErrNotSupported)
+}
+
+// Unlock releases an advisory lock of a file,
+// if the file supports locking.
+func Unlock(f File) error {
+	if l, ok := f.(LockableFile); ok {
+		return l.Unlock()
+	}
+	return NewPathError("unlock", f.Name(), ErrNotSupported)
+}
+
+// LockRange acquires an advisory lock for a byte range of a file,
+// if the file supports byte range locks.
+func LockRange(f File, offset, length int64, t LockType, wait bool) error {
+	if l, ok := f.(RangeLockableFile); ok {
+		return l.LockRange(offset, length, t, wait)
+	}
+	return NewPathError("lock", f.Name(), ErrNotSupported)
+}
+
+// UnlockRange releases an advisory lock for a byte range of a file,
+// if the file supports byte range locks.
+func UnlockRange(f File, offset, length int64) error {
+	if l, ok := f.(RangeLockableFile); ok {
+		return l.UnlockRange(offset, length)
+	}
+	return NewPathError("unlock", f.Name(), ErrNotSupported)
+}
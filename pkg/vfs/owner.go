@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+// OwnerInfo is an optional interface for the system specific
+// value returned by FileInfo.Sys() providing the ownership
+// of a file. A negative id indicates an unknown owner.
+type OwnerInfo interface {
+	Uid() int
+	Gid() int
+}
+
+// Owner returns the numeric uid and gid of the file described
+// by the given FileInfo. If the ownership is not available
+// ok is false.
+func Owner(fi FileInfo) (uid, gid int, ok bool) {
+	if fi == nil {
+		return -1, -1, false
+	}
+	if o, ok := fi.Sys().(OwnerInfo); ok {
+		uid, gid := o.Uid(), o.Gid()
+		return uid, gid, uid >= 0 && gid >= 0
+	}
+	return sysOwner(fi.Sys())
+}
+
+// Chown changes the numeric uid and gid of the named file,
+// if the filesystem supports ownership.
+func Chown(fs FileSystem, name string, uid, gid int) error {
+	if o, ok := fs.(OwnershipFileSystem); ok {
+		return o.Chown(name, uid, gid)
+	}
+	return NewPathError("chown", name, ErrNotSupported)
+}
+
+// Lchown changes the numeric uid and gid of the named file,
+// if the filesystem supports ownership. If the file is a symbolic
+// link, the link itself is changed.
+func Lchown(fs FileSystem, name string, uid, gid int) error {
+	if o, ok := fs.(OwnershipFileSystem); ok {
+		return o.Lchown(name, uid, gid)
+	}
+	return NewPathError("lchown", name, ErrNotSupported)
+}
@@ -0,0 +1,306 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ParallelWalkOptions describes the behaviour of WalkParallel.
+type ParallelWalkOptions struct {
+	// Workers is the maximum number of directories processed
+	// concurrently. The default is the number of CPUs.
+	Workers int
+	// Ordered calls the walk function sequentially in the same
+	// order as WalkDir. Only the directories are read concurrently.
+	Ordered bool
+}
+
+// WalkParallel walks the file tree rooted at root, calling fn for each file
+// or directory in the tree, including root, like WalkDir. The directories
+// are read concurrently by a pool of workers.
+//
+// Without the Ordered option, fn is called concurrently for entries
+// of different directories, but sequentially and in lexical order for the
+// entries of a single directory. Therefore, fn must be safe for concurrent
+// use and the order of the calls is not deterministic.
+// With the Ordered option, fn is called from the calling goroutine
+// in the order used by WalkDir, while the directories are read ahead
+// concurrently (possibly also directories skipped by fn).
+//
+// SkipDir and SkipAll have the same meaning as for WalkDir. The walk
+// is aborted with the first error returned by fn or with the error of
+// the context if the context is cancelled. WalkParallel does not return
+// before all pending calls of fn are finished.
+func WalkParallel(ctx context.Context, fs FileSystem, root string, fn WalkDirFunc, opts *ParallelWalkOptions) error {
+	w := &parallelWalker{ctx: ctx, fs: fs, fn: fn}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Workers <= 0 {
+		w.opts.Workers = runtime.NumCPU()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := fs.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		w.pool = newWalkPool(w.opts.Workers)
+		stop := context.AfterFunc(ctx, func() { w.fail(ctx.Err()) })
+		err = w.walk(root, dirEntry(info))
+		stop()
+		w.pool.close()
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+type parallelWalker struct {
+	ctx  context.Context
+	fs   FileSystem
+	fn   WalkDirFunc
+	opts ParallelWalkOptions
+	pool *walkPool
+
+	lock sync.Mutex
+	err  error
+}
+
+func (w *parallelWalker) walk(root string, d DirEntry) error {
+	if w.opts.Ordered {
+		if err := w.fn(root, d, nil); err != nil || !d.IsDir() {
+			return err
+		}
+		err := w.ordered(root, d, w.prefetch(root))
+		if err == nil {
+			err = w.ctx.Err()
+		}
+		return err
+	}
+
+	if err := w.fn(root, d, nil); err != nil || !d.IsDir() {
+		return err
+	}
+	w.pool.submit(func() { w.visit(root, d) })
+	w.pool.wait()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+// fail stops the walk. The first error is kept
+// as result of the walk.
+func (w *parallelWalker) fail(err error) {
+	w.lock.Lock()
+	if w.err == nil && err != SkipAll {
+		w.err = err
+	}
+	w.lock.Unlock()
+	w.pool.stop()
+}
+
+// visit reads the directory path and calls the walk function
+// for its entries. Sub directories are scheduled for the workers.
+func (w *parallelWalker) visit(path string, d DirEntry) {
+	entries, err := ReadDirEntries(w.fs, path)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		if err = w.fn(path, d, err); err != nil && err != SkipDir {
+			w.fail(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if w.pool.stopped() {
+			return
+		}
+		if err := w.ctx.Err(); err != nil {
+			w.fail(err)
+			return
+		}
+		p := Join(w.fs, path, e.Name())
+		if err := w.fn(p, e, nil); err != nil {
+			if err == SkipDir {
+				if e.IsDir() {
+					continue
+				}
+				break
+			}
+			w.fail(err)
+			return
+		}
+		if e.IsDir() {
+			w.pool.submit(func() { w.visit(p, e) })
+		}
+	}
+}
+
+// walkListing is the result of reading a directory ahead.
+type walkListing struct {
+	done    chan struct{}
+	entries []DirEntry
+	err     error
+}
+
+func (w *parallelWalker) prefetch(path string) *walkListing {
+	l := &walkListing{done: make(chan struct{})}
+	w.pool.submit(func() {
+		l.entries, l.err = ReadDirEntries(w.fs, path)
+		close(l.done)
+	})
+	return l
+}
+
+// ordered calls the walk function for the entries of a directory
+// in lexical order. The sub directories are read ahead by the workers.
+func (w *parallelWalker) ordered(path string, d DirEntry, l *walkListing) error {
+	select {
+	case <-l.done:
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+	if l.err != nil {
+		// Second call, to report ReadDir error.
+		if err := w.fn(path, d, l.err); err != nil && err != SkipDir {
+			return err
+		}
+		return nil
+	}
+
+	lists := make([]*walkListing, len(l.entries))
+	for i, e := range l.entries {
+		if e.IsDir() {
+			lists[i] = w.prefetch(Join(w.fs, path, e.Name()))
+		}
+	}
+	for i, e := range l.entries {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		p := Join(w.fs, path, e.Name())
+		err := w.fn(p, e, nil)
+		if err == nil && e.IsDir() {
+			err = w.ordered(p, e, lists[i])
+		}
+		if err != nil {
+			if err == SkipDir {
+				if e.IsDir() {
+					continue
+				}
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// walkPool is a pool of workers with an unbounded queue,
+// which allows the workers to schedule further jobs.
+type walkPool struct {
+	lock    sync.Mutex
+	cond    sync.Cond
+	queue   []func()
+	pending int
+	done    bool
+}
+
+func newWalkPool(n int) *walkPool {
+	p := &walkPool{}
+	p.cond.L = &p.lock
+	for i := 0; i < n; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *walkPool) work() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for {
+		for len(p.queue) == 0 && !p.done {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			return
+		}
+		job := p.queue[0]
+		p.queue = p.queue[1:]
+		p.lock.Unlock()
+		job()
+		p.lock.Lock()
+		p.pending--
+		if p.pending == 0 {
+			p.cond.Broadcast()
+		}
+	}
+}
+
+// submit schedules a job. It is ignored
+// if the pool is already stopped.
+func (p *walkPool) submit(job func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.done {
+		return
+	}
+	p.pending++
+	p.queue = append(p.queue, job)
+	p.cond.Broadcast()
+}
+
+// wait waits until all scheduled jobs are finished.
+func (p *walkPool) wait() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for p.pending > 0 {
+		p.cond.Wait()
+	}
+}
+
+// stop discards all jobs not yet started.
+func (p *walkPool) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done = true
+	p.pending -= len(p.queue)
+	p.queue = nil
+	p.cond.Broadcast()
+}
+
+func (p *walkPool) stopped() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.done
+}
+
+// close stops the pool and waits for
+// the running jobs.
+func (p *walkPool) close() {
+	p.stop()
+	p.wait()
+}
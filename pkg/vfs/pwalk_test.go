@@ -0,0 +1,162 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("parallel walk", func() {
+	var fs VFS
+	var expected []string
+
+	sequential := func(skip string) []string {
+		var found []string
+		ExpectWithOffset(1, WalkDir(fs, "/", func(path string, d DirEntry, err error) error {
+			ExpectWithOffset(1, err).To(Succeed())
+			found = append(found, path)
+			if path == skip {
+				return SkipDir
+			}
+			return nil
+		})).To(Succeed())
+		return found
+	}
+
+	parallel := func(opts *ParallelWalkOptions, skip string) []string {
+		var lock sync.Mutex
+		var found []string
+		ExpectWithOffset(1, WalkParallel(context.Background(), fs, "/", func(path string, d DirEntry, err error) error {
+			ExpectWithOffset(1, err).To(Succeed())
+			lock.Lock()
+			defer lock.Unlock()
+			found = append(found, path)
+			if path == skip {
+				return SkipDir
+			}
+			return nil
+		}, opts)).To(Succeed())
+		return found
+	}
+
+	BeforeEach(func() {
+		fs = New(memoryfs.New())
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 5; j++ {
+				dir := fmt.Sprintf("/d%d/d%d", i, j)
+				Expect(fs.MkdirAll(dir, os.ModePerm)).To(Succeed())
+				ExpectFileCreate(fs, dir+"/a", nil, nil)
+				ExpectFileCreate(fs, dir+"/b", nil, nil)
+			}
+		}
+		expected = sequential("")
+	})
+
+	It("visits all entries", func() {
+		Expect(parallel(&ParallelWalkOptions{Workers: 4}, "")).To(ConsistOf(expected))
+	})
+
+	It("visits all entries with defaults", func() {
+		Expect(parallel(nil, "")).To(ConsistOf(expected))
+	})
+
+	It("visits entries in order", func() {
+		Expect(parallel(&ParallelWalkOptions{Workers: 4, Ordered: true}, "")).To(Equal(expected))
+	})
+
+	It("skips directories", func() {
+		found := parallel(&ParallelWalkOptions{Workers: 4}, "/d2")
+		Expect(found).To(ConsistOf(sequential("/d2")))
+		Expect(found).NotTo(ContainElement("/d2/d0"))
+		Expect(parallel(&ParallelWalkOptions{Workers: 4, Ordered: true}, "/d2")).To(Equal(sequential("/d2")))
+	})
+
+	It("skips remaining files of directory", func() {
+		found := parallel(&ParallelWalkOptions{Workers: 4}, "/d1/d1/a")
+		Expect(found).To(ConsistOf(sequential("/d1/d1/a")))
+		Expect(found).NotTo(ContainElement("/d1/d1/b"))
+		Expect(parallel(&ParallelWalkOptions{Workers: 4, Ordered: true}, "/d1/d1/a")).To(Equal(sequential("/d1/d1/a")))
+	})
+
+	It("skips all", func() {
+		var found []string
+		Expect(WalkParallel(context.Background(), fs, "/", func(path string, d DirEntry, err error) error {
+			found = append(found, path)
+			if path == "/d0/d0" {
+				return SkipAll
+			}
+			return nil
+		}, &ParallelWalkOptions{Ordered: true})).To(Succeed())
+		Expect(found).To(Equal([]string{"/", "/d0", "/d0/d0"}))
+	})
+
+	It("aborts on error", func() {
+		for _, ordered := range []bool{false, true} {
+			err := WalkParallel(context.Background(), fs, "/", func(path string, d DirEntry, err error) error {
+				if path == "/d3/d4/b" {
+					return fmt.Errorf("failed %s", path)
+				}
+				return nil
+			}, &ParallelWalkOptions{Workers: 4, Ordered: ordered})
+			Expect(err).To(MatchError("failed /d3/d4/b"))
+		}
+	})
+
+	It("reports missing root", func() {
+		err := WalkParallel(context.Background(), fs, "/missing", func(path string, d DirEntry, err error) error {
+			return err
+		}, nil)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("is cancelled by context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(WalkParallel(ctx, fs, "/", func(path string, d DirEntry, err error) error {
+			return nil
+		}, nil)).To(MatchError(context.Canceled))
+
+		for _, ordered := range []bool{false, true} {
+			ctx, cancel = context.WithCancel(context.Background())
+			var lock sync.Mutex
+			var found []string
+			err := WalkParallel(ctx, fs, "/", func(path string, d DirEntry, err error) error {
+				lock.Lock()
+				defer lock.Unlock()
+				found = append(found, path)
+				if path == "/d0/d0" {
+					cancel()
+				}
+				return nil
+			}, &ParallelWalkOptions{Workers: 2, Ordered: ordered})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(len(found)).To(BeNumerically("<", len(expected)))
+		}
+	})
+})
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"hash"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SyncOptions describes the behaviour of Sync.
+type SyncOptions struct {
+	// Delete removes entries of the destination not
+	// present in the source.
+	Delete bool
+	// DryRun only reports the required changes
+	// without modifying the destination.
+	DryRun bool
+	// Checksum compares the content of all files with the
+	// same size. By default, files with the same size and
+	// modification time are considered unchanged.
+	Checksum bool
+	// Hash is the hash used to compare file content.
+	// The default is sha256.
+	Hash func() hash.Hash
+	// Mode describes additional aspects to be preserved. Permissions
+	// and modification times are always preserved.
+	Mode CopyMode
+}
+
+// Sync makes the destination tree identical to the source tree with
+// minimal writes: only added, changed or type-changed entries are
+// written, entries with changed permissions are only updated by
+// a Chmod and files with equal content, but a different modification
+// time by a Chtimes. Entries not present in the source are only removed with
+// SyncOptions.Delete. Sync returns the list of applied (or for a dry-run
+// the required) changes as differences of the source compared to the
+// destination.
+// The permissions and modification times of directories are applied
+// after their content has been synchronized. The source must exist.
+func Sync(srcfs FileSystem, src string, dstfs FileSystem, dst string, opts *SyncOptions) ([]DiffEntry, error) {
+	var o SyncOptions
+	if opts != nil {
+		o = *opts
+	}
+	src = Trim(srcfs, src)
+	dst = Trim(dstfs, dst)
+	if _, err := srcfs.Lstat(src); err != nil {
+		return nil, err
+	}
+	diffs, err := Diff(dstfs, dst, srcfs, src, &DiffOptions{ModTime: !o.Checksum, Hash: o.Hash, Times: true})
+	if err != nil {
+		return nil, err
+	}
+
+	copts := &CopyOptions{Mode: o.Mode | CopyPermissions | CopyTimes}
+	var changes []DiffEntry
+	// dirs are the directories to be finished with the
+	// information whether the permissions must be changed.
+	dirs := map[string]bool{}
+	for _, d := range diffs {
+		if d.Kind == DiffRemoved && (!o.Delete || d.Path == ".") {
+			continue
+		}
+		changes = append(changes, d)
+		if o.DryRun {
+			continue
+		}
+		spath := Join(srcfs, src, d.Path)
+		dpath := Join(dstfs, dst, d.Path)
+		switch d.Kind {
+		case DiffRemoved:
+			err = dstfs.RemoveAll(dpath)
+		case DiffTypeChanged:
+			err = dstfs.RemoveAll(dpath)
+			if err == nil {
+				err = Copy(srcfs, spath, dstfs, dpath, copts)
+			}
+		case DiffAdded, DiffModified:
+			err = Copy(srcfs, spath, dstfs, dpath, copts)
+		case DiffModeChanged:
+			if d.New.IsDir() {
+				dirs[d.Path] = true
+				continue
+			}
+			err = dstfs.Chmod(dpath, d.New.Mode())
+			continue
+		case DiffTimeChanged:
+			err = dstfs.Chtimes(dpath, d.New.ModTime(), d.New.ModTime())
+			continue
+		}
+		if err != nil {
+			return changes, err
+		}
+		// the modification times of the parent directories are changed
+		for p := d.Path; p != "."; {
+			p = path.Dir(p)
+			if _, ok := dirs[p]; !ok {
+				dirs[p] = false
+			}
+		}
+	}
+	return changes, finishDirs(srcfs, src, dstfs, dst, dirs)
+}
+
+// finishDirs applies the permissions (if required) and the modification
+// times of source directories to the destination, deepest first.
+func finishDirs(srcfs FileSystem, src string, dstfs FileSystem, dst string, dirs map[string]bool) error {
+	list := make([]string, 0, len(dirs))
+	for p := range dirs {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		di, dj := syncDepth(list[i]), syncDepth(list[j])
+		if di != dj {
+			return di > dj
+		}
+		return list[i] < list[j]
+	})
+	for _, p := range list {
+		fi, err := srcfs.Lstat(Join(srcfs, src, p))
+		if err != nil {
+			return err
+		}
+		dpath := Join(dstfs, dst, p)
+		if dirs[p] {
+			if err := dstfs.Chmod(dpath, fi.Mode()); err != nil {
+				return err
+			}
+		}
+		if err := dstfs.Chtimes(dpath, fi.ModTime(), fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncDepth(p string) int {
+	if p == "." {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/osfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	. "github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var _ = Describe("sync", func() {
+	var src FileSystem
+	var dst FileSystem
+
+	changes := func(list []DiffEntry, err error) []string {
+		ExpectWithOffset(1, err).To(Succeed())
+		var result []string
+		for _, e := range list {
+			result = append(result, e.String())
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		src = memoryfs.New()
+		Expect(src.MkdirAll("/d1/d2", os.ModePerm)).To(Succeed())
+		ExpectFileCreate(src, "/d1/f1", []byte("content"), nil)
+		ExpectFileCreate(src, "/d1/d2/f2", []byte("other"), nil)
+		Expect(src.Symlink("d1/f1", "/link")).To(Succeed())
+		dst = memoryfs.New()
+	})
+
+	It("synchronizes empty destination", func() {
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"A d1", "A link"}))
+		Expect(Diff(src, "/", dst, "/", nil)).To(BeEmpty())
+	})
+
+	It("does not touch unchanged files", func() {
+		_, err := Sync(src, "/", dst, "/", nil)
+		Expect(err).To(Succeed())
+		ExpectFileCreate(src, "/d1/f1", []byte("changed"), nil)
+
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"M d1/f1"}))
+		ExpectFileContent(dst, "/d1/f1", "changed")
+
+		w, err := Watch(dst, "/", true)
+		Expect(err).To(Succeed())
+		defer w.Close()
+		Expect(Sync(src, "/", dst, "/", &SyncOptions{Checksum: true})).To(BeEmpty())
+		ExpectNoEvent(w)
+	})
+
+	It("deletes entries optionally", func() {
+		Expect(dst.MkdirAll("/d3", os.ModePerm)).To(Succeed())
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"A d1", "A link"}))
+		Expect(Exists(dst, "/d3")).To(BeTrue())
+		Expect(changes(Sync(src, "/", dst, "/", &SyncOptions{Delete: true}))).To(Equal([]string{"D d3"}))
+		Expect(Exists(dst, "/d3")).To(BeFalse())
+	})
+
+	It("updates types and modes", func() {
+		_, err := Sync(src, "/", dst, "/", nil)
+		Expect(err).To(Succeed())
+		Expect(src.Remove("/link")).To(Succeed())
+		Expect(src.Mkdir("/link", os.ModePerm)).To(Succeed())
+		Expect(src.Chmod("/d1/f1", 0o600)).To(Succeed())
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"P d1/f1", "T link"}))
+		Expect(Diff(src, "/", dst, "/", nil)).To(BeEmpty())
+	})
+
+	It("aligns modification times of equal files", func() {
+		_, err := Sync(src, "/", dst, "/", nil)
+		Expect(err).To(Succeed())
+		fi, err := src.Stat("/d1/f1")
+		Expect(err).To(Succeed())
+		mtime := fi.ModTime().Add(time.Hour)
+		Expect(src.Chtimes("/d1/f1", mtime, mtime)).To(Succeed())
+
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"t d1/f1"}))
+		fi, err = dst.Stat("/d1/f1")
+		Expect(err).To(Succeed())
+		Expect(fi.ModTime().Equal(mtime)).To(BeTrue())
+		Expect(Sync(src, "/", dst, "/", nil)).To(BeEmpty())
+	})
+
+	It("rejects a missing source", func() {
+		Expect(dst.MkdirAll("/d3", os.ModePerm)).To(Succeed())
+		_, err := Sync(src, "/missing", dst, "/", &SyncOptions{Delete: true})
+		Expect(IsErrNotExist(err)).To(BeTrue())
+		Expect(Exists(dst, "/d3")).To(BeTrue())
+	})
+
+	It("finishes directories after their content", func() {
+		_, err := Sync(src, "/", dst, "/", nil)
+		Expect(err).To(Succeed())
+		ExpectFileCreate(src, "/d1/f3", []byte("new"), nil)
+		Expect(src.Chmod("/d1", 0o555|os.ModeDir)).To(Succeed())
+		fi, err := src.Stat("/d1")
+		Expect(err).To(Succeed())
+		mtime := fi.ModTime()
+
+		Expect(changes(Sync(src, "/", dst, "/", nil))).To(Equal([]string{"P d1", "A d1/f3"}))
+		ExpectFileContent(dst, "/d1/f3", "new")
+		fi, err = dst.Stat("/d1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o555)))
+		Expect(fi.ModTime().Equal(mtime)).To(BeTrue())
+		Expect(Sync(src, "/", dst, "/", nil)).To(BeEmpty())
+	})
+
+	It("reports changes in dry-run mode", func() {
+		Expect(dst.MkdirAll("/d3", os.ModePerm)).To(Succeed())
+		Expect(changes(Sync(src, "/", dst, "/", &SyncOptions{Delete: true, DryRun: true}))).To(Equal([]string{"A d1", "D d3", "A link"}))
+		ExpectFolders(dst, "/", []string{"d3"}, nil)
+	})
+
+	It("materializes trees on disk", func() {
+		tmp, err := osfs.NewTempFileSystem()
+		Expect(err).To(Succeed())
+		defer Cleanup(tmp)
+		Expect(tmp.Mkdir("/target", os.ModePerm)).To(Succeed())
+		_, err = Sync(src, "/", tmp, "/target", nil)
+		Expect(err).To(Succeed())
+		ExpectFileContent(tmp, "/target/d1/d2/f2", "other")
+		Expect(Sync(src, "/", tmp, "/target", nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"syscall"
+)
+
+func sysOwner(sys interface{}) (int, int, bool) {
+	if st, ok := sys.(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid), true
+	}
+	return -1, -1, false
+}
+
+func sysNlink(sys interface{}) (uint64, bool) {
+	if st, ok := sys.(*syscall.Stat_t); ok {
+		return uint64(st.Nlink), true
+	}
+	return 0, false
+}
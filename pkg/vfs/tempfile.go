@@ -51,6 +51,12 @@ func nextRandom() string {
 // to find the pathname of the file. It is the caller's responsibility
 // to remove the file when no longer needed.
 func TempFile(fs FileSystem, dir, pattern string) (f File, err error) {
+	return tempFile(fs, dir, pattern, 0600)
+}
+
+// tempFile creates a new temporary file with the given permissions
+// (before umask).
+func tempFile(fs FileSystem, dir, pattern string, perm FileMode) (f File, err error) {
 	if dir == "" {
 		dir = fs.FSTempDir()
 	}
@@ -65,7 +71,7 @@ func TempFile(fs FileSystem, dir, pattern string) (f File, err error) {
 	nconflict := 0
 	for i := 0; i < 10000; i++ {
 		name := filepath.Join(dir, prefix+nextRandom()+suffix)
-		f, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		f, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
 		if IsErrExist(err) {
 			if nconflict++; nconflict > 10 {
 				randmu.Lock()
@@ -0,0 +1,315 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// UnifiedDiff provides a unified text diff for the content of two
+// regular files with the given number of context lines.
+// A non-existing file is handled like an empty file (/dev/null).
+// For files with binary content only a short note is provided.
+// An empty string is returned for equal content.
+func UnifiedDiff(oldfs FileSystem, oldpath string, newfs FileSystem, newpath string, context int) (string, error) {
+	oname, odata, err := diffContent(oldfs, oldpath, "a")
+	if err != nil {
+		return "", err
+	}
+	nname, ndata, err := diffContent(newfs, newpath, "b")
+	if err != nil {
+		return "", err
+	}
+	return Unified(oname, odata, nname, ndata, context), nil
+}
+
+func diffContent(fs FileSystem, path, prefix string) (string, []byte, error) {
+	data, err := ReadFile(fs, path)
+	if err != nil {
+		if IsErrNotExist(err) {
+			return "/dev/null", nil, nil
+		}
+		return "", nil, err
+	}
+	return prefix + "/" + strings.TrimPrefix(path, PathSeparatorString), data, nil
+}
+
+// Unified provides a unified text diff for two contents.
+// An empty string is returned for equal content.
+func Unified(oldname string, old []byte, newname string, new []byte, context int) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(new, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldname, newname)
+	}
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldname, newname)
+	for _, h := range hunks(edits, context) {
+		ostart, ocount, nstart, ncount := 1, 0, 1, 0
+		for _, e := range edits[:h[0]] {
+			if e.op != '+' {
+				ostart++
+			}
+			if e.op != '-' {
+				nstart++
+			}
+		}
+		for _, e := range edits[h[0]:h[1]] {
+			if e.op != '+' {
+				ocount++
+			}
+			if e.op != '-' {
+				ncount++
+			}
+		}
+		if ocount == 0 {
+			ostart--
+		}
+		if ncount == 0 {
+			nstart--
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(ostart, ocount), hunkRange(nstart, ncount))
+		for _, e := range edits[h[0]:h[1]] {
+			buf.WriteByte(e.op)
+			buf.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits a content into lines keeping the
+// line terminators.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			i = len(data) - 1
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+type lineEdit struct {
+	op   byte
+	line string
+}
+
+// diffLines computes a minimal line edit script with the linear
+// space variant of the Myers algorithm (divide and conquer based
+// on the middle snake).
+func diffLines(a, b []string) []lineEdit {
+	ids := map[string]int{}
+	d := &lineDiffer{
+		a:   lineIds(ids, a),
+		b:   lineIds(ids, b),
+		del: make([]bool, len(a)),
+		ins: make([]bool, len(b)),
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && d.del[i]:
+			edits = append(edits, lineEdit{'-', a[i]})
+			i++
+		case j < len(b) && d.ins[j]:
+			edits = append(edits, lineEdit{'+', b[j]})
+			j++
+		default:
+			edits = append(edits, lineEdit{' ', a[i]})
+			i++
+			j++
+		}
+	}
+	return edits
+}
+
+// lineIds maps lines to numbers to speed up comparisons.
+func lineIds(ids map[string]int, lines []string) []int {
+	r := make([]int, len(lines))
+	for i, l := range lines {
+		id, ok := ids[l]
+		if !ok {
+			id = len(ids)
+			ids[l] = id
+		}
+		r[i] = id
+	}
+	return r
+}
+
+type lineDiffer struct {
+	a, b []int
+	// del and ins mark the deleted lines of a
+	// and the inserted lines of b.
+	del, ins []bool
+}
+
+// compare marks the changes for the given ranges of a and b.
+func (d *lineDiffer) compare(alo, ahi, blo, bhi int) {
+	for alo < ahi && blo < bhi && d.a[alo] == d.b[blo] {
+		alo++
+		blo++
+	}
+	for alo < ahi && blo < bhi && d.a[ahi-1] == d.b[bhi-1] {
+		ahi--
+		bhi--
+	}
+	if alo < ahi && blo < bhi {
+		x, y, ok := d.bisect(d.a[alo:ahi], d.b[blo:bhi])
+		x += alo
+		y += blo
+		if ok && (x != alo || y != blo) && (x != ahi || y != bhi) {
+			d.compare(alo, x, blo, y)
+			d.compare(x, ahi, y, bhi)
+			return
+		}
+	}
+	for i := alo; i < ahi; i++ {
+		d.del[i] = true
+	}
+	for j := blo; j < bhi; j++ {
+		d.ins[j] = true
+	}
+}
+
+// bisect searches the middle snake of a shortest edit path
+// running the algorithm forward and backward until the
+// paths overlap. It provides the point to split the problem.
+func (d *lineDiffer) bisect(a, b []int) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxd := (n + m + 1) / 2
+	off := maxd
+	vf := make([]int, 2*maxd+2)
+	vb := make([]int, 2*maxd+2)
+	for i := range vf {
+		vf[i] = -1
+		vb[i] = -1
+	}
+	vf[off+1] = 0
+	vb[off+1] = 0
+	delta := n - m
+	// the paths overlap in the forward pass, if delta is odd
+	front := delta%2 != 0
+	// offsets for the start and end of the k loops to
+	// prevent mapping of space beyond the grid
+	fstart, fend, bstart, bend := 0, 0, 0, 0
+	for step := 0; step < maxd; step++ {
+		for k := -step + fstart; k <= step-fend; k += 2 {
+			i := off + k
+			var x int
+			if k == -step || (k != step && vf[i-1] < vf[i+1]) {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[i] = x
+			switch {
+			case x > n:
+				fend += 2
+			case y > m:
+				fstart += 2
+			case front:
+				j := off + delta - k
+				if j >= 0 && j < len(vb) && vb[j] != -1 && x >= n-vb[j] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -step + bstart; k <= step-bend; k += 2 {
+			i := off + k
+			var x int
+			if k == -step || (k != step && vb[i-1] < vb[i+1]) {
+				x = vb[i+1]
+			} else {
+				x = vb[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			vb[i] = x
+			switch {
+			case x > n:
+				bend += 2
+			case y > m:
+				bstart += 2
+			case !front:
+				j := off + delta - k
+				if j >= 0 && j < len(vf) && vf[j] != -1 {
+					fx := vf[j]
+					if fx >= n-x {
+						return fx, fx - (j - off), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// hunks provides the index ranges of the edit script
+// covering the changes with the given number of context lines.
+func hunks(edits []lineEdit, context int) [][2]int {
+	var result [][2]int
+	for i, e := range edits {
+		if e.op == ' ' {
+			continue
+		}
+		start, end := i-context, i+1+context
+		if start < 0 {
+			start = 0
+		}
+		if end > len(edits) {
+			end = len(edits)
+		}
+		if l := len(result) - 1; l >= 0 && start <= result[l][1] {
+			result[l][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+	}
+	return result
+}
@@ -20,6 +20,7 @@ package vfs
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -428,42 +429,85 @@ func IsFile(fs FileSystem, path string) (bool, error) {
 	return fi.Mode()&os.ModeType == 0, nil
 }
 
-func CopyFile(srcfs FileSystem, src string, dstfs FileSystem, dst string) error {
+// CopyMode describes optional aspects of files to be preserved
+// by CopyFile and CopyDir.
+type CopyMode uint
+
+const (
+	// CopyOwnership preserves the uid and gid of copied entries,
+	// if available for the source and supported by the target filesystem.
+	CopyOwnership CopyMode = 1 << iota
+	// CopyTimes preserves the modification times of copied
+	// files and directories.
+	CopyTimes
+	// CopyPermissions preserves the permissions of copied
+	// files and directories.
+	CopyPermissions
+)
 
-	fi, err := srcfs.Lstat(src)
-	if err != nil {
-		return err
+func copyMode(mode []CopyMode) CopyMode {
+	var m CopyMode
+	for _, e := range mode {
+		m |= e
 	}
-	if !fi.Mode().IsRegular() {
-		return errors.New("no regular file")
+	return m
+}
+
+func copyOwnership(fi FileInfo, dstfs FileSystem, dst string, mode CopyMode) error {
+	if mode&CopyOwnership == 0 {
+		return nil
 	}
-	s, err := srcfs.Open(src)
-	if err != nil {
-		return err
+	uid, gid, ok := Owner(fi)
+	if !ok {
+		return nil
 	}
-	defer s.Close()
+	err := Lchown(dstfs, dst, uid, gid)
+	if IsErrNotSupported(err) {
+		return nil
+	}
+	return err
+}
 
-	d, err := dstfs.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode()&os.ModePerm)
+// CopyFile copies a regular file, attempting to preserve permissions
+// and extended attributes.
+// Optionally, additional aspects like the ownership can be preserved.
+// For more options, see Copy.
+func CopyFile(srcfs FileSystem, src string, dstfs FileSystem, dst string, mode ...CopyMode) error {
+	fi, err := srcfs.Lstat(src)
 	if err != nil {
 		return err
 	}
-	defer d.Close()
+	if !fi.Mode().IsRegular() {
+		return errors.New("no regular file")
+	}
+	return Copy(srcfs, src, dstfs, dst, &CopyOptions{Mode: copyMode(mode) | CopyPermissions})
+}
 
-	_, err = io.Copy(d, s)
-	if err != nil {
-		return err
+func copyTimes(fi FileInfo, dstfs FileSystem, dst string, mode CopyMode) error {
+	if mode&CopyTimes == 0 {
+		return nil
 	}
-	return dstfs.Chmod(dst, fi.Mode())
+	return dstfs.Chtimes(dst, fi.ModTime(), fi.ModTime())
 }
 
-// CopyDir recursively copies a directory tree, attempting to preserve permissions.
+// CopyDir recursively copies a directory tree, attempting to preserve permissions
+// and extended attributes, if supported by both filesystems.
 // Source directory must exist, destination directory may exist.
-// Symlinks are ignored and skipped.
-func CopyDir(srcfs FileSystem, src string, dstfs FileSystem, dst string) error {
+// Symlinks are copied as symlinks.
+// Optionally, additional aspects like the ownership can be preserved.
+// For more options, see Copy.
+func CopyDir(srcfs FileSystem, src string, dstfs FileSystem, dst string, mode ...CopyMode) error {
+	return CopyDirContext(context.Background(), srcfs, src, dstfs, dst, mode...)
+}
+
+// CopyDirContext recursively copies a directory tree like CopyDir.
+// The operation is aborted with the error of the context,
+// if the context is cancelled.
+func CopyDirContext(ctx context.Context, srcfs FileSystem, src string, dstfs FileSystem, dst string, mode ...CopyMode) error {
 	src = Trim(srcfs, src)
 	dst = Trim(dstfs, dst)
 
-	si, err := srcfs.Stat(src)
+	si, err := StatContext(ctx, srcfs, src)
 	if err != nil {
 		return err
 	}
@@ -471,7 +515,7 @@ func CopyDir(srcfs FileSystem, src string, dstfs FileSystem, dst string) error {
 		return NewPathError("CopyDir", src, ErrNotDir)
 	}
 
-	di, err := dstfs.Stat(dst)
+	di, err := StatContext(ctx, dstfs, dst)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
@@ -479,42 +523,11 @@ func CopyDir(srcfs FileSystem, src string, dstfs FileSystem, dst string) error {
 		return NewPathError("CopyDir", dst, ErrNotDir)
 	}
 
-	err = dstfs.MkdirAll(dst, si.Mode())
-	if err != nil {
-		return err
-	}
-
-	entries, err := ReadDir(srcfs, src)
+	src, err = EvalSymlinks(srcfs, src)
 	if err != nil {
 		return err
 	}
-
-	for _, entry := range entries {
-		srcPath := Join(srcfs, src, entry.Name())
-		dstPath := Join(dstfs, dst, entry.Name())
-
-		if entry.IsDir() {
-			err = CopyDir(srcfs, srcPath, dstfs, dstPath)
-		} else {
-			// Skip symlinks.
-			if entry.Mode()&os.ModeSymlink != 0 {
-				var old string
-				old, err = srcfs.Readlink(srcPath)
-				if err == nil {
-					err = dstfs.Symlink(old, dstPath)
-				}
-				if err == nil {
-					err = os.Chmod(dst, entry.Mode())
-				}
-			} else {
-				err = CopyFile(srcfs, srcPath, dstfs, dstPath)
-			}
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return CopyContext(ctx, srcfs, src, dstfs, dst, &CopyOptions{Mode: copyMode(mode) | CopyPermissions})
 }
 
 func Touch(fs FileSystem, path string, perm os.FileMode) error {
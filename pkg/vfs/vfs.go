@@ -19,6 +19,7 @@
 package vfs
 
 import (
+	"context"
 	"os"
 )
 
@@ -97,6 +98,10 @@ func (fs *vfs) Walk(path string, fn WalkFunc) error {
 	return Walk(fs, path, fn)
 }
 
+func (fs *vfs) Glob(pattern string) ([]string, error) {
+	return Glob(fs, pattern)
+}
+
 func (fs *vfs) Exists(path string) (bool, error) {
 	return Exists(fs, path)
 }
@@ -125,6 +130,10 @@ func (fs *vfs) WriteFile(path string, data []byte, mode os.FileMode) error {
 	return WriteFile(fs, path, data, mode)
 }
 
+func (fs *vfs) WriteFileAtomic(path string, data []byte, mode os.FileMode) error {
+	return WriteFileAtomic(fs, path, data, mode)
+}
+
 func (fs *vfs) ReadDir(path string) ([]os.FileInfo, error) {
 	return ReadDir(fs, path)
 }
@@ -144,3 +153,95 @@ func (fs *vfs) TempDir(dir, prefix string) (string, error) {
 func (fs *vfs) Cleanup() error {
 	return Cleanup(fs.FileSystem)
 }
+
+func (fs *vfs) Chown(name string, uid, gid int) error {
+	return Chown(fs.FileSystem, name, uid, gid)
+}
+
+func (fs *vfs) Lchown(name string, uid, gid int) error {
+	return Lchown(fs.FileSystem, name, uid, gid)
+}
+
+func (fs *vfs) Link(oldname, newname string) error {
+	return Link(fs.FileSystem, oldname, newname)
+}
+
+func (fs *vfs) Watch(path string, recursive bool) (Watcher, error) {
+	return Watch(fs.FileSystem, path, recursive)
+}
+
+func (fs *vfs) Getxattr(name, attr string) ([]byte, error) {
+	return Getxattr(fs.FileSystem, name, attr)
+}
+
+func (fs *vfs) Setxattr(name, attr string, data []byte, flags int) error {
+	return Setxattr(fs.FileSystem, name, attr, data, flags)
+}
+
+func (fs *vfs) Listxattr(name string) ([]string, error) {
+	return Listxattr(fs.FileSystem, name)
+}
+
+func (fs *vfs) Removexattr(name, attr string) error {
+	return Removexattr(fs.FileSystem, name, attr)
+}
+
+func (fs *vfs) Lgetxattr(name, attr string) ([]byte, error) {
+	return Lgetxattr(fs.FileSystem, name, attr)
+}
+
+func (fs *vfs) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return Lsetxattr(fs.FileSystem, name, attr, data, flags)
+}
+
+func (fs *vfs) Llistxattr(name string) ([]string, error) {
+	return Llistxattr(fs.FileSystem, name)
+}
+
+func (fs *vfs) Lremovexattr(name, attr string) error {
+	return Lremovexattr(fs.FileSystem, name, attr)
+}
+
+func (fs *vfs) CreateContext(ctx context.Context, name string) (File, error) {
+	return CreateContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) OpenContext(ctx context.Context, name string) (File, error) {
+	return OpenContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) OpenFileContext(ctx context.Context, name string, flags int, perm FileMode) (File, error) {
+	return OpenFileContext(ctx, fs.FileSystem, name, flags, perm)
+}
+
+func (fs *vfs) StatContext(ctx context.Context, name string) (FileInfo, error) {
+	return StatContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) LstatContext(ctx context.Context, name string) (FileInfo, error) {
+	return LstatContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) ReadDirContext(ctx context.Context, name string) ([]DirEntry, error) {
+	return ReadDirContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) MkdirContext(ctx context.Context, name string, perm FileMode) error {
+	return MkdirContext(ctx, fs.FileSystem, name, perm)
+}
+
+func (fs *vfs) MkdirAllContext(ctx context.Context, path string, perm FileMode) error {
+	return MkdirAllContext(ctx, fs.FileSystem, path, perm)
+}
+
+func (fs *vfs) RemoveContext(ctx context.Context, name string) error {
+	return RemoveContext(ctx, fs.FileSystem, name)
+}
+
+func (fs *vfs) RemoveAllContext(ctx context.Context, path string) error {
+	return RemoveAllContext(ctx, fs.FileSystem, path)
+}
+
+func (fs *vfs) RenameContext(ctx context.Context, oldname, newname string) error {
+	return RenameContext(ctx, fs.FileSystem, oldname, newname)
+}
@@ -146,6 +146,66 @@ var _ = Describe("filesystem", func() {
 			})
 		})
 
+		Context("WriteFileAtomic", func() {
+			It("write non-existing file", func() {
+				Expect(WriteFileAtomic(fs, "f1", []byte("content"), 0o640)).To(Succeed())
+				ExpectFileContent(fs, "f1", "content")
+				fi, err := fs.Stat("f1")
+				Expect(err).To(Succeed())
+				Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+				Expect(ReadDir(fs, "/")).To(HaveLen(1))
+			})
+
+			It("preserves mode and ownership", func() {
+				ExpectFileCreate(fs, "/f1", []byte("This is a test"), nil)
+				Expect(fs.Chmod("/f1", 0o604)).To(Succeed())
+				Expect(fs.Chown("/f1", 4711, 42)).To(Succeed())
+				Expect(WriteFileAtomic(fs, "/f1", []byte("other"), os.ModePerm)).To(Succeed())
+				ExpectFileContent(fs, "/f1", "other")
+				fi, err := fs.Stat("/f1")
+				Expect(err).To(Succeed())
+				Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0o604)))
+				uid, gid, _ := Owner(fi)
+				Expect([]int{uid, gid}).To(Equal([]int{4711, 42}))
+			})
+
+			It("follows symbolic links", func() {
+				Expect(fs.MkdirAll("/d1", os.ModePerm)).To(Succeed())
+				ExpectFileCreate(fs, "/d1/f1", []byte("This is a test"), nil)
+				Expect(fs.Symlink("d1/f1", "/link")).To(Succeed())
+				Expect(WriteFileAtomic(fs, "/link", []byte("other"), os.ModePerm)).To(Succeed())
+				Expect(fs.Readlink("/link")).To(Equal("d1/f1"))
+				ExpectFileContent(fs, "/d1/f1", "other")
+				Expect(ReadDir(fs, "/d1")).To(HaveLen(1))
+			})
+
+			It("discards aborted content", func() {
+				ExpectFileCreate(fs, "/f1", []byte("This is a test"), nil)
+				w, err := NewAtomicWriter(fs, "/f1", os.ModePerm)
+				Expect(err).To(Succeed())
+				_, err = w.Write([]byte("other"))
+				Expect(err).To(Succeed())
+				ExpectFileContent(fs, "/f1", "This is a test")
+				Expect(w.Abort()).To(Succeed())
+				ExpectFileContent(fs, "/f1", "This is a test")
+				Expect(ReadDir(fs, "/")).To(HaveLen(1))
+			})
+
+			It("rejects directories", func() {
+				Expect(fs.MkdirAll("/d1", os.ModePerm)).To(Succeed())
+				Expect(WriteFileAtomic(fs, "/d1", nil, os.ModePerm)).To(MatchError(ErrNotFile))
+			})
+
+			It("fails for different filesystems", func() {
+				fs := &crossDevice{memoryfs.New()}
+				ExpectFileCreate(fs, "/f1", []byte("This is a test"), nil)
+				err := WriteFileAtomic(fs, "/f1", []byte("other"), os.ModePerm)
+				Expect(IsErrCrossDevice(err)).To(BeTrue())
+				ExpectFileContent(fs, "/f1", "This is a test")
+				Expect(ReadDir(fs, "/")).To(HaveLen(1))
+			})
+		})
+
 		Context("ReadDir", func() {
 			It("read all directories of a subpath", func() {
 				Expect(fs.MkdirAll("/d1/d11/d111", os.ModePerm)).To(Succeed())
@@ -161,6 +221,69 @@ var _ = Describe("filesystem", func() {
 			})
 		})
 
+		Context("CopyDir", func() {
+			BeforeEach(func() {
+				Expect(fs.MkdirAll("/src/d1", os.ModePerm)).To(Succeed())
+				ExpectFileCreate(fs, "/src/d1/f1", []byte("content"), nil)
+				Expect(fs.Symlink("d1/f1", "/src/link")).To(Succeed())
+				Expect(fs.Chown("/src/d1/f1", 4711, 42)).To(Succeed())
+				Expect(fs.Lchown("/src/link", 4712, 43)).To(Succeed())
+			})
+
+			owner := func(fs FileSystem, path string) []int {
+				fi, err := fs.Lstat(path)
+				Expect(err).To(Succeed())
+				uid, gid, ok := Owner(fi)
+				Expect(ok).To(BeTrue())
+				return []int{uid, gid}
+			}
+
+			It("copies tree", func() {
+				dst := memoryfs.New()
+				Expect(CopyDir(fs, "/src", dst, "/dst")).To(Succeed())
+				ExpectFolders(dst, "/dst", []string{"d1", "link"}, nil)
+				ExpectFileContent(dst, "/dst/d1/f1", "content")
+				Expect(dst.Readlink("/dst/link")).To(Equal("d1/f1"))
+				Expect(owner(dst, "/dst/d1/f1")).To(Equal([]int{os.Getuid(), os.Getgid()}))
+			})
+
+			It("preserves ownership", func() {
+				dst := memoryfs.New()
+				Expect(CopyDir(fs, "/src", dst, "/dst", CopyOwnership)).To(Succeed())
+				Expect(owner(dst, "/dst/d1/f1")).To(Equal([]int{4711, 42}))
+				Expect(owner(dst, "/dst/link")).To(Equal([]int{4712, 43}))
+			})
+
+			It("preserves extended attributes", func() {
+				Expect(fs.Setxattr("/src/d1", "user.dir", []byte("dir"), 0)).To(Succeed())
+				Expect(fs.Setxattr("/src/d1/f1", "user.file", []byte("file"), 0)).To(Succeed())
+				dst := memoryfs.New()
+				Expect(CopyDir(fs, "/src", dst, "/dst")).To(Succeed())
+				Expect(Getxattr(dst, "/dst/d1", "user.dir")).To(Equal([]byte("dir")))
+				Expect(Getxattr(dst, "/dst/d1/f1", "user.file")).To(Equal([]byte("file")))
+			})
+
+			It("ignores failing extended attributes", func() {
+				Expect(fs.Setxattr("/src/d1/f1", "security.file", []byte("file"), 0)).To(Succeed())
+				dst := &xattrDenied{memoryfs.New().(XattrFileSystem)}
+				Expect(CopyDir(fs, "/src", dst, "/dst")).To(Succeed())
+				ExpectFileContent(dst, "/dst/d1/f1", "content")
+			})
+
+			It("propagates other extended attribute errors", func() {
+				Expect(fs.Setxattr("/src/d1/f1", "user.file", []byte("file"), 0)).To(Succeed())
+				dst := &xattrFailing{memoryfs.New().(XattrFileSystem)}
+				Expect(CopyDir(fs, "/src", dst, "/dst")).To(MatchError(os.ErrInvalid))
+			})
+
+			It("ignores missing ownership support", func() {
+				dst, err := yamlfs.New(nil)
+				Expect(err).To(Succeed())
+				Expect(CopyDir(fs, "/src", dst, "/dst", CopyOwnership)).To(Succeed())
+				ExpectFileContent(dst, "/dst/d1/f1", "content")
+			})
+		})
+
 		Context("Rel", func() {
 			It("sub path", func() {
 				Expect(fs.Rel("/", "/sub")).To(Equal("sub"))
@@ -330,3 +453,27 @@ d1:
 		})
 	})
 })
+
+type crossDevice struct {
+	FileSystem
+}
+
+func (fs *crossDevice) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrCrossDevice}
+}
+
+type xattrDenied struct {
+	XattrFileSystem
+}
+
+func (fs *xattrDenied) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return NewPathError("lsetxattr", name, os.ErrPermission)
+}
+
+type xattrFailing struct {
+	XattrFileSystem
+}
+
+func (fs *xattrFailing) Lsetxattr(name, attr string, data []byte, flags int) error {
+	return NewPathError("lsetxattr", name, os.ErrInvalid)
+}
@@ -19,6 +19,8 @@
 package vfs
 
 import (
+	"context"
+	"io/fs"
 	"os"
 	"sort"
 
@@ -43,7 +45,7 @@ func readDirNames(fs FileSystem, dirname string) ([]string, error) {
 }
 
 // adapted from https://golang.org/src/path/filepath/path.go
-func walkFS(fs FileSystem, path string, info os.FileInfo, err error, walkFn WalkFunc) error {
+func walkFS(ctx context.Context, fs FileSystem, path string, info os.FileInfo, err error, walkFn WalkFunc) error {
 	err1 := walkFn(path, info, err)
 	if err != nil || err1 != nil {
 		if err1 == SkipDir {
@@ -66,10 +68,13 @@ func walkFS(fs FileSystem, path string, info os.FileInfo, err error, walkFn Walk
 	}
 
 	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		filename := Join(fs, path, name)
-		fileInfo, err := fs.Lstat(filename)
+		fileInfo, err := LstatContext(ctx, fs, filename)
 
-		err = walkFS(fs, filename, fileInfo, err, walkFn)
+		err = walkFS(ctx, fs, filename, fileInfo, err, walkFn)
 		if err != nil {
 			if err == SkipDir {
 				return nil
@@ -85,6 +90,76 @@ type WalkFunc = filepath.WalkFunc
 var SkipDir = filepath.SkipDir
 
 func Walk(fs FileSystem, root string, walkFn WalkFunc) error {
+	return WalkContext(context.Background(), fs, root, walkFn)
+}
+
+// WalkContext walks the file tree like Walk. The walk is aborted
+// with the error of the context, if the context is cancelled.
+func WalkContext(ctx context.Context, fs FileSystem, root string, walkFn WalkFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := LstatContext(ctx, fs, root)
+	return walkFS(ctx, fs, root, info, err, walkFn)
+}
+
+// WalkDirFunc is the type of the function called by WalkDir
+// to visit each file or directory.
+type WalkDirFunc = fs.WalkDirFunc
+
+var SkipAll = fs.SkipAll
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root, like filepath.WalkDir.
+// In contrast to Walk, it uses File.ReadDir to read the directories
+// and avoids calling Lstat for every visited entry.
+// The files are walked in lexical order, symbolic links are not followed.
+func WalkDir(fs FileSystem, root string, fn WalkDirFunc) error {
 	info, err := fs.Lstat(root)
-	return walkFS(fs, root, info, err, walkFn)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fs, root, dirEntry(info), fn)
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func dirEntry(info FileInfo) DirEntry {
+	return fs.FileInfoToDirEntry(info)
+}
+
+// adapted from https://golang.org/src/path/filepath/path.go
+func walkDir(fs FileSystem, path string, d DirEntry, fn WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == SkipDir && d.IsDir() {
+			// Successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := ReadDirEntries(fs, path)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		err = fn(path, d, err)
+		if err != nil {
+			if err == SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		if err := walkDir(fs, Join(fs, path, e.Name()), e, fn); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
 }
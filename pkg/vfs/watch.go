@@ -0,0 +1,99 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"strings"
+)
+
+// EventOp describes the kind of change reported by a watch event.
+// Multiple kinds may be combined.
+type EventOp uint32
+
+const (
+	// EventCreate reports a new entry.
+	EventCreate EventOp = 1 << iota
+	// EventWrite reports a modified file content.
+	EventWrite
+	// EventRemove reports a removed entry.
+	EventRemove
+	// EventRename reports an entry renamed to another name.
+	// The new name is reported with EventCreate.
+	EventRename
+	// EventChmod reports changed attributes, like the mode,
+	// the ownership or the modification time.
+	EventChmod
+)
+
+var eventOpNames = []string{"CREATE", "WRITE", "REMOVE", "RENAME", "CHMOD"}
+
+func (op EventOp) Has(o EventOp) bool {
+	return op&o == o
+}
+
+func (op EventOp) String() string {
+	var names []string
+	for i, n := range eventOpNames {
+		if op&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event describes a change of a filesystem entry.
+type Event struct {
+	// Path is the path of the changed entry in the watched filesystem.
+	Path string
+	Op   EventOp
+}
+
+func (e Event) String() string {
+	return e.Op.String() + " " + e.Path
+}
+
+// Watcher provides the events for a watched path.
+type Watcher interface {
+	// Events provides the channel delivering the events.
+	// It is closed when the watcher is closed.
+	Events() <-chan Event
+	// Close stops watching.
+	Close() error
+}
+
+// WatchableFileSystem is an optional interface for filesystems
+// supporting change notifications.
+type WatchableFileSystem interface {
+	FileSystem
+
+	// Watch reports changes of the given path. For a directory,
+	// changes of the directory and its entries are reported.
+	// If recursive is set, the changes of all entries in the
+	// complete sub tree are reported.
+	Watch(path string, recursive bool) (Watcher, error)
+}
+
+// Watch starts watching the given path,
+// if the filesystem supports change notifications.
+func Watch(fs FileSystem, path string, recursive bool) (Watcher, error) {
+	if w, ok := fs.(WatchableFileSystem); ok {
+		return w.Watch(path, recursive)
+	}
+	return nil, NewPathError("watch", path, ErrNotSupported)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package vfs
+
+import (
+	"sort"
+)
+
+func xattrFS(fs FileSystem) (XattrFileSystem, bool) {
+	x, ok := fs.(XattrFileSystem)
+	return x, ok
+}
+
+// Getxattr returns the value of an extended attribute of the named file,
+// if the filesystem supports extended attributes.
+func Getxattr(fs FileSystem, name, attr string) ([]byte, error) {
+	if x, ok := xattrFS(fs); ok {
+		return x.Getxattr(name, attr)
+	}
+	return nil, NewPathError("getxattr", name, ErrNotSupported)
+}
+
+// Setxattr sets the value of an extended attribute of the named file,
+// if the filesystem supports extended attributes.
+func Setxattr(fs FileSystem, name, attr string, data []byte, flags int) error {
+	if x, ok := xattrFS(fs); ok {
+		return x.Setxattr(name, attr, data, flags)
+	}
+	return NewPathError("setxattr", name, ErrNotSupported)
+}
+
+// Listxattr returns the names of the extended attributes of the named file,
+// if the filesystem supports extended attributes.
+func Listxattr(fs FileSystem, name string) ([]string, error) {
+	if x, ok := xattrFS(fs); ok {
+		return x.Listxattr(name)
+	}
+	return nil, NewPathError("listxattr", name, ErrNotSupported)
+}
+
+// Removexattr removes an extended attribute of the named file,
+// if the filesystem supports extended attributes.
+func Removexattr(fs FileSystem, name, attr string) error {
+	if x, ok := xattrFS(fs); ok {
+		return x.Removexattr(name, attr)
+	}
+	return NewPathError("removexattr", name, ErrNotSupported)
+}
+
+// Lgetxattr is like Getxattr, but does not follow a final symbolic link.
+func Lgetxattr(fs FileSystem, name, attr string) ([]byte, error) {
+	if x, ok := xattrFS(fs); ok {
+		return x.Lgetxattr(name, attr)
+	}
+	return nil, NewPathError("lgetxattr", name, ErrNotSupported)
+}
+
+// Lsetxattr is like Setxattr, but does not follow a final symbolic link.
+func Lsetxattr(fs FileSystem, name, attr string, data []byte, flags int) error {
+	if x, ok := xattrFS(fs); ok {
+		return x.Lsetxattr(name, attr, data, flags)
+	}
+	return NewPathError("lsetxattr", name, ErrNotSupported)
+}
+
+// Llistxattr is like Listxattr, but does not follow a final symbolic link.
+func Llistxattr(fs FileSystem, name string) ([]string, error) {
+	if x, ok := xattrFS(fs); ok {
+		return x.Llistxattr(name)
+	}
+	return nil, NewPathError("llistxattr", name, ErrNotSupported)
+}
+
+// Lremovexattr is like Removexattr, but does not follow a final symbolic link.
+func Lremovexattr(fs FileSystem, name, attr string) error {
+	if x, ok := xattrFS(fs); ok {
+		return x.Lremovexattr(name, attr)
+	}
+	return NewPathError("lremovexattr", name, ErrNotSupported)
+}
+
+// CopyXattrs copies all extended attributes of a file to
+// another file without following symbolic links.
+// If one of the filesystems does not support extended attributes
+// nothing is done.
+func CopyXattrs(srcfs FileSystem, src string, dstfs FileSystem, dst string) error {
+	if _, ok := xattrFS(srcfs); !ok {
+		return nil
+	}
+	if _, ok := xattrFS(dstfs); !ok {
+		return nil
+	}
+	attrs, err := Llistxattr(srcfs, src)
+	if err != nil {
+		if IsErrNotSupported(err) {
+			return nil
+		}
+		return err
+	}
+	sort.Strings(attrs)
+	for _, a := range attrs {
+		data, err := Lgetxattr(srcfs, src, a)
+		if err != nil {
+			if IsErrNoAttr(err) {
+				continue
+			}
+			return err
+		}
+		err = Lsetxattr(dstfs, dst, a, data, 0)
+		if err != nil {
+			if IsErrNotSupported(err) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
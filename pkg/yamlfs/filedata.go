@@ -284,7 +284,7 @@ func (f *fileDirData) Add(name string, s utils.FileData) error {
 	return nil
 }
 
-func (f fileDirData) Del(name string) error {
+func (f *fileDirData) Del(name string) error {
 	_, ok := f.entries[name]
 	if !ok {
 		return vfs.ErrNotExist
@@ -67,6 +67,12 @@ func NewByData(data map[interface{}]interface{}) *YamlFileSystem {
 	return &YamlFileSystem{utils.NewFSSupport("YamlFileSystem", newFileDirData(data), adapter), data}
 }
 
+var _ vfs.WatchableFileSystem = (*YamlFileSystem)(nil)
+
+func (y *YamlFileSystem) Watch(path string, recursive bool) (vfs.Watcher, error) {
+	return vfs.Watch(y.FileSystem, path, recursive)
+}
+
 func (y *YamlFileSystem) Data() ([]byte, error) {
 	return yaml.Marshal(y.data)
 }
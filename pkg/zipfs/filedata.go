@@ -0,0 +1,146 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package zipfs
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mandelsoft/vfs/pkg/utils"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+type fileData struct {
+	sync.Mutex
+	file    *zip.File
+	data    []byte
+	entries map[string]*fileData
+	mode    os.FileMode
+	modtime time.Time
+
+	once sync.Once
+	err  error
+}
+
+var _ utils.FileData = &fileData{}
+var _ utils.FileDataSize = &fileData{}
+
+func newDir(mode os.FileMode, modtime time.Time) *fileData {
+	return &fileData{mode: os.ModeDir | (mode & os.ModePerm), modtime: modtime, entries: map[string]*fileData{}}
+}
+
+// load decompresses the content of a regular file
+// on first access.
+func (f *fileData) load() error {
+	f.once.Do(func() {
+		if f.file == nil {
+			return
+		}
+		var r io.ReadCloser
+		r, f.err = f.file.Open()
+		if f.err != nil {
+			return
+		}
+		defer r.Close()
+		f.data, f.err = io.ReadAll(r)
+	})
+	return f.err
+}
+
+func (f *fileData) Data() []byte {
+	f.load()
+	return f.data
+}
+
+func (f *fileData) SetData(data []byte) {
+	f.load()
+	f.data = data
+}
+
+func (f *fileData) Size() int64 {
+	if f.file != nil && f.IsFile() {
+		return int64(f.file.UncompressedSize64)
+	}
+	return int64(len(f.data))
+}
+
+func (f *fileData) Files() []os.FileInfo {
+	files := make([]os.FileInfo, 0, len(f.entries))
+	for n, e := range f.entries {
+		files = append(files, utils.NewFileInfo(n, e))
+	}
+	return files
+}
+
+func (f *fileData) IsDir() bool {
+	return f.mode&os.ModeType == os.ModeDir
+}
+
+func (f *fileData) IsFile() bool {
+	return f.mode&os.ModeType == 0
+}
+
+func (f *fileData) IsSymlink() bool {
+	return (f.mode & os.ModeType) == os.ModeSymlink
+}
+
+func (f *fileData) GetSymlink() string {
+	if f.IsSymlink() {
+		return string(f.data)
+	}
+	return ""
+}
+
+func (f *fileData) Mode() os.FileMode {
+	return f.mode
+}
+
+func (f *fileData) SetMode(mode os.FileMode) {
+	f.mode = mode
+}
+
+func (f *fileData) ModTime() time.Time {
+	return f.modtime
+}
+
+func (f *fileData) SetModTime(mtime time.Time) {
+	f.modtime = mtime
+}
+
+func (f *fileData) GetEntry(name string) (utils.FileDataDirAccess, error) {
+	if !f.IsDir() {
+		return nil, vfs.ErrNotDir
+	}
+	e, ok := f.entries[name]
+	if ok {
+		return e, nil
+	}
+	return nil, vfs.ErrNotExist
+}
+
+func (f *fileData) Add(name string, s utils.FileData) error {
+	return vfs.ErrReadOnly
+}
+
+func (f *fileData) Del(name string) error {
+	return vfs.ErrReadOnly
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package zipfs
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mandelsoft/vfs/pkg/readonlyfs"
+	"github.com/mandelsoft/vfs/pkg/utils"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+// ZipFileSystem is a read-only filesystem
+// providing the content of a zip archive.
+type ZipFileSystem struct {
+	vfs.FileSystem
+	files  map[string]*fileData
+	closer io.Closer
+}
+
+type zipFileSystemAdapter struct{}
+
+// New provides a filesystem for the zip archive read from r,
+// which has the given size.
+func New(r io.ReaderAt, size int64) (*ZipFileSystem, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewByReader(reader)
+}
+
+// NewByReader provides a filesystem for the given zip reader.
+func NewByReader(reader *zip.Reader) (*ZipFileSystem, error) {
+	var modtime time.Time
+	if len(reader.File) > 0 {
+		modtime = reader.File[0].Modified
+	}
+	root := newDir(0o755, modtime)
+	files := map[string]*fileData{vfs.PathSeparatorString: root}
+
+	var mkdir func(p string, f *zip.File) (*fileData, error)
+	mkdir = func(p string, f *zip.File) (*fileData, error) {
+		if d := files[p]; d != nil {
+			if !d.IsDir() {
+				return nil, fmt.Errorf("%s: %s", p, vfs.ErrNotDir)
+			}
+			return d, nil
+		}
+		parent, err := mkdir(path.Dir(p), f)
+		if err != nil {
+			return nil, err
+		}
+		d := newDir(0o755, f.Modified)
+		parent.entries[path.Base(p)] = d
+		files[p] = d
+		return d, nil
+	}
+
+	for _, f := range reader.File {
+		p := path.Clean(vfs.PathSeparatorString + f.Name)
+		if p == vfs.PathSeparatorString {
+			continue
+		}
+		parent, err := mkdir(path.Dir(p), f)
+		if err != nil {
+			return nil, err
+		}
+		mode := f.Mode()
+		if mode.IsDir() || strings.HasSuffix(f.Name, "/") {
+			d, err := mkdir(p, f)
+			if err != nil {
+				return nil, err
+			}
+			d.mode = os.ModeDir | (mode & os.ModePerm)
+			d.modtime = f.Modified
+			continue
+		}
+		if d := files[p]; d != nil && d.IsDir() {
+			return nil, fmt.Errorf("%s: %s", p, vfs.ErrExist)
+		}
+		e := &fileData{file: f, mode: mode, modtime: f.Modified}
+		if e.IsSymlink() {
+			if err := e.load(); err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+		} else if !e.IsFile() {
+			// other special files are not supported by zip archives
+			continue
+		}
+		parent.entries[path.Base(p)] = e
+		files[p] = e
+	}
+
+	fs := utils.NewFSSupport("ZipFileSystem", root, zipFileSystemAdapter{})
+	return &ZipFileSystem{FileSystem: readonlyfs.New(fs), files: files}, nil
+}
+
+// NewByFile provides a filesystem for the zip archive
+// provided by the given file.
+func NewByFile(file vfs.File) (*ZipFileSystem, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return New(file, fi.Size())
+}
+
+// NewByPath provides a filesystem for the zip archive found at the given
+// path of a virtual filesystem. If no filesystem is given, the
+// operating system filesystem is used. The archive file is kept open
+// until the filesystem is cleaned up with vfs.Cleanup.
+func NewByPath(fs vfs.FileSystem, path string) (*ZipFileSystem, error) {
+	var file vfs.File
+	var err error
+
+	if fs == nil {
+		file, err = os.Open(path)
+	} else {
+		file, err = fs.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	z, err := NewByFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	z.closer = file
+	return z, nil
+}
+
+func (z *ZipFileSystem) Cleanup() error {
+	if z.closer != nil {
+		c := z.closer
+		z.closer = nil
+		return c.Close()
+	}
+	return nil
+}
+
+// decompress the content of a regular file before it is opened.
+func (z *ZipFileSystem) load(name string) error {
+	p, err := vfs.Canonical(z, name, true)
+	if err != nil {
+		return nil // let the filesystem report the error
+	}
+	if f := z.files[p]; f != nil {
+		if err := f.load(); err != nil {
+			return &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+func (z *ZipFileSystem) Open(name string) (vfs.File, error) {
+	if err := z.load(name); err != nil {
+		return nil, err
+	}
+	return z.FileSystem.Open(name)
+}
+
+func (z *ZipFileSystem) OpenFile(name string, flags int, perm os.FileMode) (vfs.File, error) {
+	if flags&(os.O_WRONLY|os.O_CREATE|os.O_RDWR) == 0 {
+		if err := z.load(name); err != nil {
+			return nil, err
+		}
+	}
+	return z.FileSystem.OpenFile(name, flags, perm)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// the adapter is never used to create new nodes,
+// because the filesystem is read-only.
+
+func (zipFileSystemAdapter) CreateFile(perm os.FileMode) utils.FileData {
+	return nil
+}
+
+func (zipFileSystemAdapter) CreateDir(perm os.FileMode) utils.FileData {
+	return nil
+}
+
+func (zipFileSystemAdapter) CreateSymlink(link string, perm os.FileMode) utils.FileData {
+	return nil
+}
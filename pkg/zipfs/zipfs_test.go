@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 Mandelsoft. All rights reserved.
+ *  This file is licensed under the Apache Software License, v. 2 except as noted
+ *  otherwise in the LICENSE file
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	. "github.com/mandelsoft/vfs/pkg/test"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+)
+
+var modtime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func addEntry(w *zip.Writer, name string, mode os.FileMode, data string) {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modtime}
+	h.SetMode(mode)
+	e, err := w.CreateHeader(h)
+	Expect(err).To(Succeed())
+	_, err = e.Write([]byte(data))
+	Expect(err).To(Succeed())
+}
+
+func archive() []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	addEntry(w, "d1/", os.ModeDir|0o700, "")
+	addEntry(w, "d1/f1", 0o600, "file1")
+	addEntry(w, "d2/d3/f2", 0o644, "file2")
+	addEntry(w, "d2/link", os.ModeSymlink|0o777, "d3/f2")
+	addEntry(w, "f3", 0o755, "file3")
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("zip filesystem", func() {
+	var fs *ZipFileSystem
+
+	BeforeEach(func() {
+		data := archive()
+		var err error
+		fs, err = New(bytes.NewReader(data), int64(len(data)))
+		Expect(err).To(Succeed())
+	})
+
+	It("provides files", func() {
+		ExpectFolders(fs, "/", []string{"d1", "d2", "f3"}, nil)
+		ExpectFolders(fs, "/d2", []string{"d3", "link"}, nil)
+		ExpectFileContent(fs, "/d1/f1", "file1")
+		ExpectFileContent(fs, "/d2/d3/f2", "file2")
+		ExpectFileContent(fs, "/f3", "file3")
+	})
+
+	It("provides metadata", func() {
+		fi, err := fs.Stat("/d1")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode()).To(Equal(os.ModeDir | 0o700))
+		Expect(fi.ModTime().Equal(modtime)).To(BeTrue())
+
+		fi, err = fs.Stat("/d2")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode()).To(Equal(os.ModeDir | 0o755))
+
+		fi, err = fs.Stat("/f3")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode()).To(Equal(os.FileMode(0o755)))
+		Expect(fi.Size()).To(Equal(int64(5)))
+		Expect(fi.ModTime().Equal(modtime)).To(BeTrue())
+	})
+
+	It("decompresses lazily", func() {
+		Expect(fs.files["/f3"].data).To(BeNil())
+		_, err := fs.Stat("/f3")
+		Expect(err).To(Succeed())
+		Expect(fs.files["/f3"].data).To(BeNil())
+		f, err := fs.Open("/f3")
+		Expect(err).To(Succeed())
+		Expect(fs.files["/f3"].data).To(Equal([]byte("file3")))
+		Expect(f.Close()).To(Succeed())
+	})
+
+	It("provides symbolic links", func() {
+		fi, err := fs.Lstat("/d2/link")
+		Expect(err).To(Succeed())
+		Expect(fi.Mode() & os.ModeType).To(Equal(os.ModeSymlink))
+		Expect(fs.Readlink("/d2/link")).To(Equal("d3/f2"))
+		ExpectFileContent(fs, "/d2/link", "file2")
+	})
+
+	It("is read-only", func() {
+		_, err := fs.Create("/f4")
+		Expect(vfs.IsErrReadOnly(err)).To(BeTrue())
+		_, err = fs.OpenFile("/f3", os.O_RDWR, 0)
+		Expect(vfs.IsErrReadOnly(err)).To(BeTrue())
+		Expect(vfs.IsErrReadOnly(fs.Remove("/f3"))).To(BeTrue())
+		Expect(vfs.IsErrReadOnly(fs.Mkdir("/d4", 0o755))).To(BeTrue())
+	})
+
+	It("is a valid io/fs filesystem", func() {
+		Expect(fstest.TestFS(vfs.AsIoFS(fs), "d1/f1", "d2/d3/f2", "f3")).To(Succeed())
+	})
+
+	It("reads archive from virtual filesystem", func() {
+		mem := memoryfs.New()
+		Expect(vfs.WriteFile(mem, "archive.zip", archive(), 0o644)).To(Succeed())
+		fs, err := NewByPath(mem, "archive.zip")
+		Expect(err).To(Succeed())
+		ExpectFileContent(fs, "/d1/f1", "file1")
+		Expect(vfs.Cleanup(fs)).To(Succeed())
+	})
+
+	It("rejects invalid archives", func() {
+		_, err := New(bytes.NewReader([]byte("no zip")), 6)
+		Expect(err).To(HaveOccurred())
+	})
+})